@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/lib/pq"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
 	"github.com/mariopavlov/nexus/backend/internal/core/usecases"
 	"github.com/mariopavlov/nexus/backend/internal/infrastructure/ai"
 	"github.com/mariopavlov/nexus/backend/internal/infrastructure/repositories/postgres"
+	"github.com/mariopavlov/nexus/backend/internal/infrastructure/storage"
+	"github.com/mariopavlov/nexus/backend/internal/infrastructure/tools"
 	"github.com/mariopavlov/nexus/backend/internal/interfaces/http/handlers"
 )
 
@@ -17,7 +24,7 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Priority, X-Priority-Token")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -29,6 +36,33 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
+// envInt reads an optional integer setting, returning 0 when it is unset.
+func envInt(name string) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return n
+}
+
+// envDuration reads an optional duration setting such as "30s", returning 0
+// when it is unset.
+func envDuration(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return d
+}
+
 func main() {
 	// Database connection
 	dbURL := os.Getenv("DATABASE_URL")
@@ -44,13 +78,180 @@ func main() {
 
 	// Repository
 	chatRepo := postgres.NewChatRepository(db)
+	summaryRepo := postgres.NewSummaryRepository(db)
+	embeddingRepo := postgres.NewEmbeddingRepository(db)
+	documentRepo := postgres.NewDocumentRepository(db)
 
 	// AI Service
-	ollamaURL := os.Getenv("OLLAMA_URL")
-	aiService := ai.NewOllamaService(ollamaURL)
+	providerNames := os.Getenv("AI_PROVIDERS")
+	if providerNames == "" {
+		providerNames = "ollama"
+	}
+
+	connectTimeout := envDuration("AI_CONNECT_TIMEOUT")
+	if connectTimeout == 0 {
+		connectTimeout = 5 * time.Second
+	}
+	responseTimeout := envDuration("AI_RESPONSE_TIMEOUT")
+	if responseTimeout == 0 {
+		responseTimeout = 5 * time.Minute
+	}
+	httpClient := ai.NewHTTPClient(connectTimeout, responseTimeout)
+
+	resilience := ai.ResilienceConfig{
+		MaxRetries:       envInt("AI_MAX_RETRIES"),
+		InitialBackoff:   envDuration("AI_RETRY_BACKOFF"),
+		FailureThreshold: envInt("AI_BREAKER_THRESHOLD"),
+		Cooldown:         envDuration("AI_BREAKER_COOLDOWN"),
+	}
+
+	ollamaURLs := strings.Split(os.Getenv("OLLAMA_URL"), ",")
+	for i := range ollamaURLs {
+		ollamaURLs[i] = strings.TrimSpace(ollamaURLs[i])
+	}
+
+	var providers []ai.Provider
+	var ollamaService ports.AIModelService
+	var modelManager ports.ModelManager
+	for _, name := range strings.Split(providerNames, ",") {
+		var service ports.AIModelService
+		switch name = strings.TrimSpace(name); name {
+		case "ollama":
+			// Several comma separated URLs are balanced as a pool
+			if len(ollamaURLs) > 1 {
+				service = ai.NewOllamaPool(context.Background(), ollamaURLs, httpClient, ai.PoolConfig{
+					HealthInterval: envDuration("OLLAMA_HEALTH_INTERVAL"),
+					HealthTimeout:  envDuration("OLLAMA_HEALTH_TIMEOUT"),
+				})
+			} else {
+				service = ai.NewOllamaService(ollamaURLs[0], httpClient)
+			}
+			ollamaService = service
+			// Ollama also supports pulling and deleting models
+			modelManager, _ = service.(ports.ModelManager)
+		case "openai":
+			service = ai.NewOpenAIService(os.Getenv("OPENAI_BASE_URL"), os.Getenv("OPENAI_API_KEY"), httpClient)
+		default:
+			log.Fatalf("Unknown AI provider: %s", name)
+		}
+		// Every provider gets its own circuit breaker
+		providers = append(providers, ai.Provider{Name: name, Service: ai.NewResilientService(name, service, resilience)})
+	}
+
+	// A single provider is used directly so model IDs stay un-namespaced
+	var aiService ports.AIModelService
+	if len(providers) == 1 {
+		aiService = providers[0].Service
+	} else {
+		rules, err := ai.ParseRoutingRules(os.Getenv("AI_ROUTES"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		aiService = ai.NewRouterService(providers, rules)
+	}
+
+	// Generations are queued per model once a concurrency limit is configured
+	modelConcurrency, err := ai.ParseModelConcurrency(os.Getenv("AI_MODEL_CONCURRENCY"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if concurrency := envInt("AI_MAX_CONCURRENCY"); concurrency > 0 || len(modelConcurrency) > 0 {
+		aiService = ai.NewSchedulingService(aiService, ai.SchedulerConfig{
+			Concurrency:      concurrency,
+			ModelConcurrency: modelConcurrency,
+			QueueSize:        envInt("AI_QUEUE_SIZE"),
+		})
+	}
+
+	// Deterministic answers are cached once RESPONSE_CACHE_SIZE is set
+	if size := envInt("RESPONSE_CACHE_SIZE"); size > 0 {
+		aiService = ai.NewCachingService(aiService, ai.CacheConfig{
+			Size: size,
+			TTL:  envDuration("RESPONSE_CACHE_TTL"),
+		})
+	}
 
 	// Use cases
-	chatUseCase := usecases.NewChatUseCase(chatRepo, aiService)
+	chatConfig := usecases.ChatConfig{
+		DefaultSystemPrompt:  os.Getenv("SYSTEM_PROMPT"),
+		DefaultContextLength: envInt("DEFAULT_CONTEXT_LENGTH"),
+		MaxContextLength:     envInt("MAX_CONTEXT_LENGTH"),
+		ResponseReserve:      envInt("CONTEXT_RESPONSE_RESERVE"),
+		HistoryLimit:         envInt("CONTEXT_HISTORY_LIMIT"),
+		CompareParallelism:   envInt("COMPARE_PARALLELISM"),
+	}
+	if chains := os.Getenv("MODEL_FALLBACKS"); chains != "" {
+		chatConfig.FallbackChains, err = usecases.ParseFallbackChains(chains)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if path := os.Getenv("SYSTEM_PROMPT_FILE"); path != "" {
+		prompt, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read system prompt file: %v", err)
+		}
+		chatConfig.DefaultSystemPrompt = string(prompt)
+	}
+
+	var summarizer *usecases.Summarizer
+	if enabled, _ := strconv.ParseBool(os.Getenv("SUMMARY_ENABLED")); enabled {
+		summarizer = usecases.NewSummarizer(chatRepo, summaryRepo, aiService, usecases.SummaryConfig{
+			Model:      os.Getenv("SUMMARY_MODEL"),
+			Threshold:  envInt("SUMMARY_THRESHOLD"),
+			KeepRecent: envInt("SUMMARY_KEEP_RECENT"),
+		})
+	}
+
+	// Semantic search and documents are enabled by configuring an embedding model
+	var indexer *usecases.MessageIndexer
+	var retriever *usecases.Retriever
+	var searchUseCase ports.SearchUseCase
+	var documentUseCase ports.DocumentUseCase
+	if embeddingModel := os.Getenv("EMBEDDING_MODEL"); embeddingModel != "" {
+		// A pool of Ollama hosts computes embeddings on its healthy hosts
+		var embeddingService ports.EmbeddingService
+		if pool, ok := ollamaService.(*ai.OllamaPool); ok {
+			embeddingService = pool.EmbeddingService(embeddingModel)
+		} else {
+			embeddingService = ai.NewOllamaEmbeddingService(ollamaURLs[0], embeddingModel, httpClient)
+		}
+		indexer = usecases.NewMessageIndexer(embeddingService, embeddingRepo)
+		retriever = usecases.NewRetriever(documentRepo, embeddingService, envInt("RETRIEVAL_TOP_K"))
+		searchUseCase = usecases.NewSearchUseCase(embeddingService, embeddingRepo)
+		documentUseCase = usecases.NewDocumentUseCase(documentRepo, embeddingService)
+	}
+
+	// Tools are opt-in, TOOLS lists the built-in tools to offer to models
+	var toolRegistry *usecases.ToolRegistry
+	if toolNames := os.Getenv("TOOLS"); toolNames != "" {
+		var enabled []ports.Tool
+		for _, name := range strings.Split(toolNames, ",") {
+			switch name = strings.TrimSpace(name); name {
+			case "current_time":
+				enabled = append(enabled, tools.NewCurrentTimeTool())
+			default:
+				log.Fatalf("Unknown tool: %s", name)
+			}
+		}
+		toolRegistry, err = usecases.NewToolRegistry(envInt("TOOL_MAX_ROUNDS"), enabled...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	attachmentStore, err := storage.NewFileAttachmentStore(os.Getenv("ATTACHMENT_DIR"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	chatUseCase := usecases.NewChatUseCase(chatRepo, aiService, usecases.ChatFeatures{
+		Summarizer:  summarizer,
+		Indexer:     indexer,
+		Retriever:   retriever,
+		Tools:       toolRegistry,
+		Attachments: attachmentStore,
+	}, chatConfig)
 
 	// HTTP Handler
 	chatHandler := handlers.NewChatHandler(chatUseCase)
@@ -60,9 +261,26 @@ func main() {
 
 	// Apply CORS middleware
 	r.Use(CORSMiddleware())
+	r.Use(handlers.PriorityMiddleware(os.Getenv("PRIORITY_TOKEN")))
 
 	// Register routes
 	chatHandler.RegisterRoutes(r)
+	if modelManager != nil {
+		adminToken := os.Getenv("ADMIN_TOKEN")
+		if adminToken == "" {
+			log.Printf("ADMIN_TOKEN is not set, the model admin routes are locked")
+		}
+		modelHandler := handlers.NewModelHandler(usecases.NewModelUseCase(modelManager), adminToken)
+		modelHandler.RegisterRoutes(r)
+	}
+	if searchUseCase != nil {
+		searchHandler := handlers.NewSearchHandler(searchUseCase)
+		searchHandler.RegisterRoutes(r)
+	}
+	if documentUseCase != nil {
+		documentHandler := handlers.NewDocumentHandler(documentUseCase)
+		documentHandler.RegisterRoutes(r)
+	}
 
 	// Start server
 	port := os.Getenv("PORT")
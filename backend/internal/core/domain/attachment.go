@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnsupportedAttachment = errors.New("unsupported attachment type")
+	ErrVisionNotSupported    = errors.New("model does not support images")
+)
+
+// Attachment is a file sent along with a message. Only its metadata is
+// stored on the message, the bytes live in the attachment store.
+type Attachment struct {
+	ID          uuid.UUID `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	// Data is loaded from the attachment store when the message is sent
+	Data []byte `json:"-"`
+}
+
+func NewAttachment(filename, contentType string, data []byte) *Attachment {
+	return &Attachment{
+		ID:          uuid.New(),
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Data:        data,
+	}
+}
+
+// IsImage reports whether the attachment is an image.
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// Attachments lists the files attached to a message.
+type Attachments []*Attachment
+
+// Value implements the driver.Valuer interface
+func (a Attachments) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements the sql.Scanner interface
+func (a *Attachments) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("unsupported type for Attachments: %T", value)
+	}
+}
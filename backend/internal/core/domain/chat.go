@@ -45,20 +45,24 @@ func (id *ChatID) Scan(value interface{}) error {
 }
 
 type Chat struct {
-	ID        ChatID    `json:"id"`
-	Title     string    `json:"title"`
-	Messages  []Message `json:"messages"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           ChatID             `json:"id"`
+	Title        string             `json:"title"`
+	SystemPrompt string             `json:"system_prompt,omitempty"`
+	Options      *GenerationOptions `json:"options,omitempty"`
+	Messages     []Message          `json:"messages"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
 }
 
-func NewChat(title string) *Chat {
+func NewChat(title string, systemPrompt string, options *GenerationOptions) *Chat {
 	now := time.Now()
 
 	return &Chat{
-		ID:        ChatID(uuid.New()),
-		Title:     title,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:           ChatID(uuid.New()),
+		Title:        title,
+		SystemPrompt: systemPrompt,
+		Options:      options,
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}
 }
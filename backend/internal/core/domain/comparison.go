@@ -0,0 +1,17 @@
+package domain
+
+// ComparisonResult is the answer of one model to a compared prompt. Error is
+// set instead of Message when the model failed.
+type ComparisonResult struct {
+	Model     string   `json:"model"`
+	Message   *Message `json:"message,omitempty"`
+	LatencyMs int64    `json:"latency_ms"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Comparison holds the answers of several models to the same user message,
+// in the order the models were requested.
+type Comparison struct {
+	UserMessage *Message           `json:"user_message"`
+	Results     []ComparisonResult `json:"results"`
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnsupportedDocument = errors.New("unsupported document: upload plain text or markdown, extract PDF text before uploading")
+	ErrEmptyDocument       = errors.New("document has no text")
+)
+
+// Document is uploaded reference text. Documents without a ChatID belong to
+// the workspace and are available to every chat.
+type Document struct {
+	ID          uuid.UUID `json:"id"`
+	ChatID      *ChatID   `json:"chat_id,omitempty"`
+	Title       string    `json:"title"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	ChunkCount  int       `json:"chunk_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func NewDocument(chatID *ChatID, title string, contentType string, size int) *Document {
+	return &Document{
+		ID:          uuid.New(),
+		ChatID:      chatID,
+		Title:       title,
+		ContentType: contentType,
+		Size:        size,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// DocumentChunk is a retrievable slice of a document.
+type DocumentChunk struct {
+	ID         uuid.UUID `json:"id"`
+	DocumentID uuid.UUID `json:"document_id"`
+	Index      int       `json:"index"`
+	Content    string    `json:"content"`
+	Embedding  []float32 `json:"-"`
+}
+
+func NewDocumentChunk(documentID uuid.UUID, index int, content string) *DocumentChunk {
+	return &DocumentChunk{
+		ID:         uuid.New(),
+		DocumentID: documentID,
+		Index:      index,
+		Content:    content,
+	}
+}
+
+// ChunkSearchResult is a chunk matched by retrieval, ranked by Score.
+type ChunkSearchResult struct {
+	Chunk         *DocumentChunk `json:"chunk"`
+	DocumentTitle string         `json:"document_title"`
+	Score         float64        `json:"score"`
+}
+
+// ChunkIDs lists the document chunks an answer cites.
+type ChunkIDs []uuid.UUID
+
+// Value implements the driver.Valuer interface
+func (ids ChunkIDs) Value() (driver.Value, error) {
+	if ids == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements the sql.Scanner interface
+func (ids *ChunkIDs) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, ids)
+	case string:
+		return json.Unmarshal([]byte(v), ids)
+	default:
+		return fmt.Errorf("unsupported type for ChunkIDs: %T", value)
+	}
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Fallback records that an answer came from another model than the one
+// requested.
+type Fallback struct {
+	RequestedModel string `json:"requested_model"`
+	// Failures lists, in order, why each model before the answering one was
+	// given up on
+	Failures []ModelFailure `json:"failures"`
+}
+
+type ModelFailure struct {
+	Model  string `json:"model"`
+	Reason string `json:"reason"`
+}
+
+// Value implements the driver.Valuer interface
+func (f *Fallback) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements the sql.Scanner interface
+func (f *Fallback) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, f)
+	case string:
+		return json.Unmarshal([]byte(v), f)
+	default:
+		return fmt.Errorf("unsupported type for Fallback: %T", value)
+	}
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrInvalidFormat           = errors.New("invalid response format")
+	ErrInvalidStructuredOutput = errors.New("response does not match the requested format")
+)
+
+// ResponseFormat asks for the answer as JSON.
+type ResponseFormat struct {
+	// Schema is either the string "json", for any JSON value, or a JSON
+	// Schema object the answer must validate against
+	Schema json.RawMessage
+	// Retry re-prompts the model once when the answer does not validate
+	Retry bool
+}
+
+// NewResponseFormat checks that raw is "json" or a schema object.
+func NewResponseFormat(raw json.RawMessage, retry bool) (*ResponseFormat, error) {
+	format := &ResponseFormat{Schema: raw, Retry: retry}
+	if !format.IsSchema() {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil || name != "json" {
+			return nil, ErrInvalidFormat
+		}
+	} else if !json.Valid(raw) {
+		return nil, ErrInvalidFormat
+	}
+	return format, nil
+}
+
+// IsSchema reports whether the format is a JSON Schema rather than "json".
+func (f *ResponseFormat) IsSchema() bool {
+	trimmed := bytes.TrimSpace(f.Schema)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "errors"
+
+var (
+	ErrGenerationNotFound  = errors.New("no generation in progress")
+	ErrGenerationCancelled = errors.New("generation cancelled")
+)
+
+// GenerationStarted identifies a generation while it runs. Either ID can be
+// used to cancel it.
+type GenerationStarted struct {
+	UserMessageID MessageID `json:"user_message_id"`
+	MessageID     MessageID `json:"message_id"`
+}
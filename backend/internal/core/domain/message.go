@@ -2,12 +2,19 @@ package domain
 
 import (
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotAnAnswer     = errors.New("message is not an assistant answer")
+)
+
 type MessageID uuid.UUID
 
 // Value implements the driver.Valuer interface
@@ -47,18 +54,71 @@ func (id *MessageID) Scan(value interface{}) error {
 type MessageRole string
 
 const (
-	UserRole    MessageRole = "user"
+	UserRole      MessageRole = "user"
 	AssistantRole MessageRole = "assistant"
 	SystemRole    MessageRole = "system"
+	// ToolRole messages carry the result of a tool call back to the model
+	ToolRole MessageRole = "tool"
+)
+
+type MessageStatus string
+
+const (
+	MessageCompleted MessageStatus = "completed"
+	// MessageCancelled answers hold what was generated before the user
+	// stopped the generation
+	MessageCancelled MessageStatus = "cancelled"
 )
 
 type Message struct {
-	ID        MessageID `json:"id"`
-	ChatID    ChatID   `json:"chat_id"`
-	Content   string   `json:"content"`
-	Role      MessageRole `json:"role"`
-	Model     string   `json:"model"`
-	CreatedAt time.Time `json:"created_at"`
+	ID       MessageID          `json:"id"`
+	ChatID   ChatID             `json:"chat_id"`
+	Content  string             `json:"content"`
+	Role     MessageRole        `json:"role"`
+	Model    string             `json:"model"`
+	Provider string             `json:"provider,omitempty"`
+	Options  *GenerationOptions `json:"options,omitempty"`
+	Status   MessageStatus      `json:"status"`
+	// ParentID is the message this one continues from, nil for the first
+	// message of a chat
+	ParentID *MessageID `json:"parent_id,omitempty"`
+	// AlternativeOf links an answer to the answer of the same turn it is an
+	// alternative to. Only the active alternative is sent as context.
+	AlternativeOf *MessageID `json:"alternative_of,omitempty"`
+	Active        bool       `json:"active"`
+	// Fallback is set when Model answered in place of the requested model
+	Fallback *Fallback        `json:"fallback,omitempty"`
+	Stats    *GenerationStats `json:"stats,omitempty"`
+	// Cached answers were served from the response cache, not generated
+	Cached      bool        `json:"cached,omitempty"`
+	Attachments Attachments `json:"attachments,omitempty"`
+	// CitedChunks are the document chunks an answer was grounded on
+	CitedChunks ChunkIDs `json:"cited_chunks,omitempty"`
+	// ToolCalls are set on assistant turns that asked for tools to be run
+	ToolCalls ToolCalls `json:"tool_calls,omitempty"`
+	// ToolCallID and ToolName identify the call a tool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	ToolName   string `json:"tool_name,omitempty"`
+	// Tools are offered to the model with this turn and are not persisted
+	Tools []ToolDefinition `json:"-"`
+	// Format constrains the answer to this turn, it is not persisted
+	Format *ResponseFormat `json:"-"`
+	// Parsed is the decoded answer of a turn that requested a format
+	Parsed json.RawMessage `json:"parsed,omitempty"`
+	// Context is only set on freshly generated answers and is not persisted
+	Context   *ContextUsage `json:"context,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	// EditedAt is set once the content has been edited, see MessageEdit
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+}
+
+// ContextUsage describes how the history sent with a generation was
+// trimmed to fit the model's context window.
+type ContextUsage struct {
+	ContextLength   int `json:"context_length"`
+	EstimatedTokens int `json:"estimated_tokens"`
+	Included        int `json:"included"`
+	Dropped         int `json:"dropped"`
 }
 
 func NewMessage(chatID ChatID, content string, role MessageRole, model string) *Message {
@@ -68,6 +128,29 @@ func NewMessage(chatID ChatID, content string, role MessageRole, model string) *
 		Content:   content,
 		Role:      role,
 		Model:     model,
+		Status:    MessageCompleted,
+		Active:    true,
 		CreatedAt: time.Now(),
 	}
 }
+
+// MessageChunk is a single piece of a streamed model response. The final chunk
+// has Done set and, once persisted, carries the saved assistant Message.
+type MessageChunk struct {
+	// Started is only set on the first chunk of a chat stream
+	Started    *GenerationStarted `json:"started,omitempty"`
+	Content    string             `json:"content"`
+	Done       bool               `json:"done"`
+	DoneReason string             `json:"done_reason,omitempty"`
+	Provider   string             `json:"provider,omitempty"`
+	// ToolCalls requested by the model, reported on the final chunk
+	ToolCalls ToolCalls `json:"tool_calls,omitempty"`
+	// QueuePosition is set while the request waits for the model, 1 being
+	// next in line
+	QueuePosition int `json:"queue_position,omitempty"`
+	// Stats and Cached are reported on the final chunk
+	Stats   *GenerationStats `json:"stats,omitempty"`
+	Cached  bool             `json:"cached,omitempty"`
+	Message *Message         `json:"message,omitempty"`
+	Err     error            `json:"-"`
+}
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageEdit keeps the content a message had before it was edited or
+// deleted.
+type MessageEdit struct {
+	ID        uuid.UUID `json:"id"`
+	MessageID MessageID `json:"message_id"`
+	// Content is the content that was replaced or deleted at EditedAt
+	Content  string    `json:"content"`
+	EditedAt time.Time `json:"edited_at"`
+	// Deleted marks the entry recorded when the message was deleted
+	Deleted bool `json:"deleted,omitempty"`
+}
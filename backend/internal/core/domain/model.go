@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrModelNotFound = errors.New("model not found")
+	// ErrProviderUnavailable means the model backend is down or overloaded
+	ErrProviderUnavailable = errors.New("model provider unavailable")
+	// ErrProviderTimeout means the model backend did not answer in time
+	ErrProviderTimeout = errors.New("model provider timed out")
+	// ErrModelFailed means the backend is up but the model could not
+	// answer, e.g. because it ran out of memory or failed to load
+	ErrModelFailed = errors.New("model failed to answer")
+	// ErrInvalidModelCopy means a model was copied onto its own name
+	ErrInvalidModelCopy = errors.New("source and destination must differ")
+)
+
+// Capabilities reported by providers.
+const (
+	CapabilityTools  = "tools"
+	CapabilityVision = "vision"
+)
+
+// ModelInfo describes a model as reported by its provider. Fields a provider
+// does not expose are left at their zero value.
+type ModelInfo struct {
+	Name              string    `json:"name"`
+	Provider          string    `json:"provider,omitempty"`
+	Family            string    `json:"family,omitempty"`
+	Families          []string  `json:"families,omitempty"`
+	Format            string    `json:"format,omitempty"`
+	ParameterSize     string    `json:"parameter_size,omitempty"`
+	ParameterCount    int64     `json:"parameter_count,omitempty"`
+	QuantizationLevel string    `json:"quantization_level,omitempty"`
+	ContextLength     int       `json:"context_length,omitempty"`
+	Capabilities      []string  `json:"capabilities,omitempty"`
+	Size              int64     `json:"size,omitempty"`
+	Digest            string    `json:"digest,omitempty"`
+	ModifiedAt        time.Time `json:"modified_at"`
+}
+
+// Supports reports whether the model has the given capability. Models whose
+// provider does not report capabilities are assumed to support everything.
+func (m *ModelInfo) Supports(capability string) bool {
+	if len(m.Capabilities) == 0 {
+		return true
+	}
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelPullProgress is a single status update while a model is downloaded.
+type ModelPullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Err       error  `json:"-"`
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// GenerationOptions controls sampling for a single generation. Fields are
+// pointers so that an explicit zero (e.g. temperature 0) can be told apart
+// from "not set".
+type GenerationOptions struct {
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	NumCtx        *int     `json:"num_ctx,omitempty"`
+	NumPredict    *int     `json:"num_predict,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	Stop          []string `json:"stop,omitempty"`
+}
+
+// Merge returns a copy of o with every field set in override taking
+// precedence. Either side may be nil.
+func (o *GenerationOptions) Merge(override *GenerationOptions) *GenerationOptions {
+	if o == nil && override == nil {
+		return nil
+	}
+
+	merged := &GenerationOptions{}
+	if o != nil {
+		*merged = *o
+	}
+	if override == nil {
+		return merged
+	}
+
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.TopK != nil {
+		merged.TopK = override.TopK
+	}
+	if override.Seed != nil {
+		merged.Seed = override.Seed
+	}
+	if override.NumCtx != nil {
+		merged.NumCtx = override.NumCtx
+	}
+	if override.NumPredict != nil {
+		merged.NumPredict = override.NumPredict
+	}
+	if override.RepeatPenalty != nil {
+		merged.RepeatPenalty = override.RepeatPenalty
+	}
+	if override.Stop != nil {
+		merged.Stop = override.Stop
+	}
+	return merged
+}
+
+// Deterministic reports whether the options ask for a reproducible answer:
+// greedy sampling or a fixed seed.
+func (o *GenerationOptions) Deterministic() bool {
+	if o == nil {
+		return false
+	}
+	return (o.Temperature != nil && *o.Temperature == 0) || o.Seed != nil
+}
+
+// Value implements the driver.Valuer interface
+func (o *GenerationOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	// Returned as a string so lib/pq does not encode it as bytea
+	return string(data), nil
+}
+
+// Scan implements the sql.Scanner interface
+func (o *GenerationOptions) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	default:
+		return fmt.Errorf("unsupported type for GenerationOptions: %T", value)
+	}
+}
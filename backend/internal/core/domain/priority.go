@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrQueueFull means a model has too many requests waiting to take another.
+var ErrQueueFull = errors.New("generation queue is full")
+
+// Priority orders requests waiting for the same model.
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	// PriorityLow is meant for background work such as summaries
+	PriorityLow Priority = "low"
+)
+
+// ParsePriority accepts the priority names, an empty name is normal.
+func ParsePriority(name string) (Priority, error) {
+	switch p := Priority(name); p {
+	case "":
+		return PriorityNormal, nil
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid priority %q, expected high, normal or low", name)
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose generations are scheduled with p.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority of ctx, normal when none was set.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
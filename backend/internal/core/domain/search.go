@@ -0,0 +1,9 @@
+package domain
+
+// MessageSearchResult is a message matched by a search, ranked by Score
+// (higher is more relevant).
+type MessageSearchResult struct {
+	ChatID  ChatID   `json:"chat_id"`
+	Message *Message `json:"message"`
+	Score   float64  `json:"score"`
+}
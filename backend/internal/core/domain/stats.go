@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GenerationStats describes how long a generation took. Durations are in
+// nanoseconds, as Ollama reports them; fields a provider does not report are
+// zero.
+type GenerationStats struct {
+	TotalDuration      time.Duration `json:"total_duration"`
+	LoadDuration       time.Duration `json:"load_duration,omitempty"`
+	PromptEvalCount    int           `json:"prompt_eval_count"`
+	PromptEvalDuration time.Duration `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int           `json:"eval_count"`
+	EvalDuration       time.Duration `json:"eval_duration,omitempty"`
+	// TokensPerSecond is the generation speed, measured over the eval
+	// duration when known and the total duration otherwise
+	TokensPerSecond float64 `json:"tokens_per_second"`
+}
+
+func NewGenerationStats(promptEvalCount, evalCount int, total, load, promptEval, eval time.Duration) *GenerationStats {
+	stats := &GenerationStats{
+		TotalDuration:      total,
+		LoadDuration:       load,
+		PromptEvalCount:    promptEvalCount,
+		PromptEvalDuration: promptEval,
+		EvalCount:          evalCount,
+		EvalDuration:       eval,
+	}
+
+	elapsed := eval
+	if elapsed <= 0 {
+		elapsed = total
+	}
+	if elapsed > 0 {
+		stats.TokensPerSecond = float64(evalCount) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// Value implements the driver.Valuer interface
+func (s *GenerationStats) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements the sql.Scanner interface
+func (s *GenerationStats) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("unsupported type for GenerationStats: %T", value)
+	}
+}
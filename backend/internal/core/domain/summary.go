@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChatSummary condenses the branch of a chat that ends at CoveredMessageID,
+// which was created at CoveredUntil. Summaries roll forward: each one
+// incorporates the previous summary of the same branch.
+type ChatSummary struct {
+	ID               uuid.UUID  `json:"id"`
+	ChatID           ChatID     `json:"chat_id"`
+	Content          string     `json:"content"`
+	Model            string     `json:"model"`
+	MessageCount     int        `json:"message_count"`
+	CoveredMessageID *MessageID `json:"covered_message_id,omitempty"`
+	CoveredUntil     time.Time  `json:"covered_until"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func NewChatSummary(chatID ChatID, content string, model string, messageCount int, covered *Message) *ChatSummary {
+	return &ChatSummary{
+		ID:               uuid.New(),
+		ChatID:           chatID,
+		Content:          content,
+		Model:            model,
+		MessageCount:     messageCount,
+		CoveredMessageID: &covered.ID,
+		CoveredUntil:     covered.CreatedAt,
+		CreatedAt:        time.Now(),
+	}
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrToolRoundsExceeded means the model kept calling tools instead of
+// answering once its tool budget was spent.
+var ErrToolRoundsExceeded = errors.New("model did not answer within its tool budget")
+
+// ToolDefinition describes a tool offered to the model.
+type ToolDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Parameters is the JSON schema of the arguments object
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is a request from the model to run a tool.
+type ToolCall struct {
+	// ID is assigned by the provider, or generated when it does not assign one
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolCalls lists the tools an assistant turn asked to run.
+type ToolCalls []ToolCall
+
+// Value implements the driver.Valuer interface
+func (c ToolCalls) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements the sql.Scanner interface
+func (c *ToolCalls) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("unsupported type for ToolCalls: %T", value)
+	}
+}
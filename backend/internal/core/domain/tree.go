@@ -0,0 +1,15 @@
+package domain
+
+// MessageNode is a message together with the messages that continue from
+// it. Alternatives of an answer are siblings of it.
+type MessageNode struct {
+	*Message
+	Children []*MessageNode `json:"children"`
+}
+
+// Branch is the path from the first message of a chat to Leaf, which a new
+// message can continue from.
+type Branch struct {
+	Leaf   *Message `json:"leaf"`
+	Length int      `json:"length"`
+}
@@ -2,7 +2,9 @@ package ports
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/mariopavlov/nexus/backend/internal/core/domain"
 )
 
@@ -12,11 +14,81 @@ type ChatRepository interface {
 	Update(ctx context.Context, chat *domain.Chat) error
 	Delete(ctx context.Context, id domain.ChatID) error
 	List(ctx context.Context, limit, offset int) ([]*domain.Chat, error)
+	// AddMessage saves the message; an active alternative takes over from the active member
+	// of its group
 	AddMessage(ctx context.Context, chatID domain.ChatID, message *domain.Message) error
+	// GetMessages, GetRecentMessages and GetAllMessages place alternatives at the time of the
+	// answer they replace, the active one first
 	GetMessages(ctx context.Context, chatID domain.ChatID, limit, offset int) ([]*domain.Message, error)
+	GetRecentMessages(ctx context.Context, chatID domain.ChatID, limit int) ([]*domain.Message, error)
+	// GetAllMessages returns every message of the chat, including all branches and alternatives
+	GetAllMessages(ctx context.Context, chatID domain.ChatID) ([]*domain.Message, error)
+	// GetAncestors returns up to limit messages of the branch ending at id in chronological
+	// order, with each answer replaced by its active alternative
+	GetAncestors(ctx context.Context, chatID domain.ChatID, id domain.MessageID, limit int) ([]*domain.Message, error)
+	// GetMessage returns domain.ErrMessageNotFound if the chat has no such message
+	GetMessage(ctx context.Context, chatID domain.ChatID, id domain.MessageID) (*domain.Message, error)
+	// GetAlternatives returns the answer id followed by its alternatives, oldest first
+	GetAlternatives(ctx context.Context, chatID domain.ChatID, id domain.MessageID) ([]*domain.Message, error)
+	// SetActiveAlternative makes active the only active answer among id and its alternatives
+	SetActiveAlternative(ctx context.Context, chatID domain.ChatID, id, active domain.MessageID) error
+	// EditMessage replaces the content, keeping the previous content as a domain.MessageEdit
+	EditMessage(ctx context.Context, chatID domain.ChatID, id domain.MessageID, content string, editedAt time.Time) error
+	// GetMessageEdits returns the previous contents of the message, oldest first
+	GetMessageEdits(ctx context.Context, chatID domain.ChatID, id domain.MessageID) ([]domain.MessageEdit, error)
+	// DeleteMessage removes the message, attaching its replies to its parent or to the
+	// alternative that takes its place
+	DeleteMessage(ctx context.Context, chatID domain.ChatID, id domain.MessageID) error
+}
+
+type SummaryRepository interface {
+	SaveSummary(ctx context.Context, summary *domain.ChatSummary) error
+	// GetLatestSummary returns the latest summary that ends at one of the
+	// messages, nil without an error when there is none
+	GetLatestSummary(ctx context.Context, chatID domain.ChatID, messageIDs []domain.MessageID) (*domain.ChatSummary, error)
 }
 
 type AIModelService interface {
 	SendMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (*domain.Message, error)
-	ListAvailableModels(ctx context.Context) ([]string, error)
+	StreamMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error)
+	// ListModels without details only reports what the provider's listing does, which may
+	// leave out the context length and capabilities
+	ListModels(ctx context.Context, detailed bool) ([]domain.ModelInfo, error)
+	GetModelInfo(ctx context.Context, name string) (*domain.ModelInfo, error)
+}
+
+type ModelManager interface {
+	PullModel(ctx context.Context, name string) (<-chan domain.ModelPullProgress, error)
+	DeleteModel(ctx context.Context, name string) error
+	CopyModel(ctx context.Context, source, destination string) error
+	PreloadModel(ctx context.Context, name string, keepAlive time.Duration) error
+}
+
+type EmbeddingService interface {
+	// Model identifies the embedding model; vectors of different models are not comparable
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+type EmbeddingRepository interface {
+	SaveMessageEmbedding(ctx context.Context, message *domain.Message, model string, embedding []float32) error
+	SearchMessages(ctx context.Context, model string, query []float32, limit int) ([]domain.MessageSearchResult, error)
+}
+
+type DocumentRepository interface {
+	// CreateDocument stores the document and its embedded chunks atomically
+	CreateDocument(ctx context.Context, document *domain.Document, chunks []*domain.DocumentChunk, model string) error
+	GetDocument(ctx context.Context, id uuid.UUID) (*domain.Document, error)
+	// ListDocuments returns the documents of a chat, or the workspace documents when chatID is nil
+	ListDocuments(ctx context.Context, chatID *domain.ChatID) ([]*domain.Document, error)
+	DeleteDocument(ctx context.Context, id uuid.UUID) error
+	// SearchChunks ranks the chunks of the chat's and the workspace documents
+	SearchChunks(ctx context.Context, chatID domain.ChatID, model string, vector []float32, limit int) ([]domain.ChunkSearchResult, error)
+}
+
+// AttachmentStore keeps the bytes of message attachments, grouped by chat.
+type AttachmentStore interface {
+	Save(ctx context.Context, chatID domain.ChatID, attachment *domain.Attachment) error
+	Load(ctx context.Context, chatID domain.ChatID, id uuid.UUID) ([]byte, error)
+	DeleteChat(ctx context.Context, chatID domain.ChatID) error
 }
@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Tool is a server-side function the model may call while answering.
+type Tool interface {
+	Name() string
+	Description() string
+	// Parameters returns the JSON schema of the arguments object
+	Parameters() json.RawMessage
+	// Execute runs the tool and returns the result handed back to the model
+	Execute(ctx context.Context, arguments json.RawMessage) (string, error)
+}
@@ -2,17 +2,49 @@ package ports
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/mariopavlov/nexus/backend/internal/core/domain"
 )
 
 type ChatUseCase interface {
-	CreateChat(ctx context.Context, title string) (*domain.Chat, error)
+	CreateChat(ctx context.Context, title string, systemPrompt string, options *domain.GenerationOptions) (*domain.Chat, error)
 	GetChat(ctx context.Context, id domain.ChatID) (*domain.Chat, error)
-	UpdateChat(ctx context.Context, id domain.ChatID, title string) (*domain.Chat, error)
+	UpdateChat(ctx context.Context, id domain.ChatID, title string, systemPrompt *string, options *domain.GenerationOptions) (*domain.Chat, error)
 	ListChats(ctx context.Context, limit, offset int) ([]*domain.Chat, error)
 	DeleteChat(ctx context.Context, id domain.ChatID) error
-	SendMessage(ctx context.Context, chatID domain.ChatID, content string, model string) (*domain.Message, error)
+	SendMessage(ctx context.Context, chatID domain.ChatID, parentID *domain.MessageID, content string, model string, options *domain.GenerationOptions, attachments domain.Attachments, format *domain.ResponseFormat) (*domain.Message, error)
+	StreamMessage(ctx context.Context, chatID domain.ChatID, parentID *domain.MessageID, content string, model string, options *domain.GenerationOptions, attachments domain.Attachments, format *domain.ResponseFormat) (<-chan domain.MessageChunk, error)
+	CompareModels(ctx context.Context, chatID domain.ChatID, parentID *domain.MessageID, content string, models []string, options *domain.GenerationOptions) (*domain.Comparison, error)
+	RegenerateMessage(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID, model string, options *domain.GenerationOptions) (*domain.Message, error)
+	ListAlternatives(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) ([]*domain.Message, error)
+	SelectAlternative(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) (*domain.Message, error)
+	EditMessage(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID, content string) (*domain.Message, error)
+	DeleteMessage(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) error
+	ListMessageEdits(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) ([]domain.MessageEdit, error)
+	GetChatTree(ctx context.Context, chatID domain.ChatID) ([]*domain.MessageNode, error)
+	ListBranches(ctx context.Context, chatID domain.ChatID) ([]domain.Branch, error)
+	CancelGeneration(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) error
 	GetChatHistory(ctx context.Context, chatID domain.ChatID, limit, offset int) ([]*domain.Message, error)
 	ListAvailableModels(ctx context.Context) ([]string, error)
+	ListModels(ctx context.Context) ([]domain.ModelInfo, error)
+	GetModelInfo(ctx context.Context, name string) (*domain.ModelInfo, error)
+}
+
+type ModelUseCase interface {
+	PullModel(ctx context.Context, name string) (<-chan domain.ModelPullProgress, error)
+	DeleteModel(ctx context.Context, name string) error
+	CopyModel(ctx context.Context, source, destination string) error
+	PreloadModel(ctx context.Context, name string, keepAlive time.Duration) error
+}
+
+type SearchUseCase interface {
+	SemanticSearch(ctx context.Context, query string, limit int) ([]domain.MessageSearchResult, error)
+}
+
+type DocumentUseCase interface {
+	UploadDocument(ctx context.Context, chatID *domain.ChatID, title string, contentType string, content []byte) (*domain.Document, error)
+	ListDocuments(ctx context.Context, chatID *domain.ChatID) ([]*domain.Document, error)
+	DeleteDocument(ctx context.Context, id uuid.UUID) error
 }
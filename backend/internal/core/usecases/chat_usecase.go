@@ -2,27 +2,45 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/mariopavlov/nexus/backend/internal/core/domain"
 	"github.com/mariopavlov/nexus/backend/internal/core/ports"
 )
 
 type chatUseCase struct {
-	chatRepo     ports.ChatRepository
-	modelService ports.AIModelService
+	chatRepo       ports.ChatRepository
+	modelService   ports.AIModelService
+	features       ChatFeatures
+	config         ChatConfig
+	contextBuilder *ContextBuilder
+	// modelInfos caches what the provider reports about each model
+	modelInfos  sync.Map
+	generations *generationTracker
 }
 
-func NewChatUseCase(chatRepo ports.ChatRepository, modelService ports.AIModelService) ports.ChatUseCase {
+func NewChatUseCase(chatRepo ports.ChatRepository, modelService ports.AIModelService, features ChatFeatures, config ChatConfig) ports.ChatUseCase {
+	config.applyDefaults()
 	return &chatUseCase{
-		chatRepo:     chatRepo,
-		modelService: modelService,
+		chatRepo:       chatRepo,
+		modelService:   modelService,
+		features:       features,
+		config:         config,
+		contextBuilder: NewContextBuilder(NewCharTokenEstimator(4)),
+		generations:    newGenerationTracker(),
 	}
 }
 
-func (uc *chatUseCase) CreateChat(ctx context.Context, title string) (*domain.Chat, error) {
-	chat := domain.NewChat(title)
+func (uc *chatUseCase) CreateChat(ctx context.Context, title string, systemPrompt string, options *domain.GenerationOptions) (*domain.Chat, error) {
+	chat := domain.NewChat(title, systemPrompt, options)
 	err := uc.chatRepo.Create(ctx, chat)
 	if err != nil {
 		return nil, err
@@ -57,56 +75,1082 @@ func (uc *chatUseCase) ListChats(ctx context.Context, limit, offset int) ([]*dom
 }
 
 func (uc *chatUseCase) DeleteChat(ctx context.Context, id domain.ChatID) error {
-	return uc.chatRepo.Delete(ctx, id)
+	if err := uc.chatRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	// The chat is gone either way, leftover files are only logged
+	if uc.features.Attachments != nil {
+		if err := uc.features.Attachments.DeleteChat(ctx, id); err != nil {
+			log.Printf("Failed to delete attachments of chat %s: %v", uuid.UUID(id), err)
+		}
+	}
+	return nil
+}
+
+// saveMessage persists the message and queues it for semantic indexing.
+func (uc *chatUseCase) saveMessage(ctx context.Context, message *domain.Message) error {
+	if err := uc.chatRepo.AddMessage(ctx, message.ChatID, message); err != nil {
+		return err
+	}
+	if uc.features.Indexer != nil {
+		uc.features.Indexer.Index(message)
+	}
+	return nil
 }
 
-func (uc *chatUseCase) SendMessage(ctx context.Context, chatID domain.ChatID, content string, model string) (*domain.Message, error) {
-	// Get chat history
-	messages, err := uc.chatRepo.GetMessages(ctx, chatID, 10, 0) // Get last 10 messages for context
+// systemPrompt returns the chat's own prompt or the server-wide default.
+func (uc *chatUseCase) systemPrompt(chat *domain.Chat) string {
+	if chat.SystemPrompt != "" {
+		return chat.SystemPrompt
+	}
+	return uc.config.DefaultSystemPrompt
+}
+
+// modelInfo returns what the provider reports about the model, or nil if
+// it cannot be described right now.
+func (uc *chatUseCase) modelInfo(ctx context.Context, model string) *domain.ModelInfo {
+	if info, ok := uc.modelInfos.Load(model); ok {
+		return info.(*domain.ModelInfo)
+	}
+
+	info, err := uc.modelService.GetModelInfo(ctx, model)
+	if err != nil {
+		log.Printf("Failed to get info for model %s: %v", model, err)
+		return nil
+	}
+
+	uc.modelInfos.Store(model, info)
+	return info
+}
+
+// contextLength returns the number of tokens the generation may use: an
+// explicit num_ctx wins over what the model reports, which is capped at
+// MaxContextLength.
+func (uc *chatUseCase) contextLength(ctx context.Context, model string, options *domain.GenerationOptions) int {
+	if options != nil && options.NumCtx != nil && *options.NumCtx > 0 {
+		return *options.NumCtx
+	}
+
+	if info := uc.modelInfo(ctx, model); info != nil && info.ContextLength > 0 {
+		return min(info.ContextLength, uc.config.MaxContextLength)
+	}
+	return uc.config.DefaultContextLength
+}
+
+// imageContentTypes are the image formats vision models accept.
+var imageContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// supportsVision reports whether images can be sent to the model. Models
+// whose provider cannot be asked are given the benefit of the doubt.
+func (uc *chatUseCase) supportsVision(ctx context.Context, model string) bool {
+	info := uc.modelInfo(ctx, model)
+	return info == nil || info.Supports(domain.CapabilityVision)
+}
+
+// checkAttachments rejects attachments the model cannot take before anything
+// about the turn is saved.
+func (uc *chatUseCase) checkAttachments(ctx context.Context, model string, attachments domain.Attachments) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	if uc.features.Attachments == nil {
+		return fmt.Errorf("%w: attachments are disabled", domain.ErrUnsupportedAttachment)
+	}
+	for _, a := range attachments {
+		if !imageContentTypes[a.ContentType] {
+			return fmt.Errorf("%w: %s", domain.ErrUnsupportedAttachment, a.ContentType)
+		}
+	}
+	if !uc.supportsVision(ctx, model) {
+		return fmt.Errorf("%w: %s", domain.ErrVisionNotSupported, model)
+	}
+	return nil
+}
+
+// loadAttachments reads the images of the selected history back from the
+// attachment store. Images that cannot be read are left out rather than
+// failing the turn.
+func (uc *chatUseCase) loadAttachments(ctx context.Context, messages []*domain.Message) {
+	for _, m := range messages {
+		for _, a := range m.Attachments {
+			if a.Data != nil {
+				continue
+			}
+			data, err := uc.features.Attachments.Load(ctx, m.ChatID, a.ID)
+			if err != nil {
+				log.Printf("Failed to load attachment %s: %v", a.ID, err)
+				continue
+			}
+			a.Data = data
+		}
+	}
+}
+
+// turn is a user message ready to be sent together with its context.
+type turn struct {
+	user *domain.Message
+	// responseID is assigned up front so the answer can be cancelled by ID
+	// while it is generated
+	responseID domain.MessageID
+	history    []*domain.Message
+	usage      domain.ContextUsage
+	// sources are the document chunks injected into the prompt
+	sources []domain.ChunkSearchResult
+	// pending holds the tool calls and results that follow the user turn
+	pending    []*domain.Message
+	toolRounds int
+	// model is the model currently answering, which differs from the
+	// requested one after a fallback
+	model     string
+	fallbacks []string
+	fallback  *domain.Fallback
+}
+
+// leaf is the latest message of the turn, which the next one continues.
+func (t *turn) leaf() domain.MessageID {
+	if len(t.pending) > 0 {
+		return t.pending[len(t.pending)-1].ID
+	}
+	return t.user.ID
+}
+
+// request returns the message to send and the history preceding it. Once
+// tools have run, the latest tool result takes the place of the user turn
+// and carries the user's generation settings.
+func (t *turn) request() (*domain.Message, []*domain.Message) {
+	if len(t.pending) == 0 {
+		user := *t.user
+		user.Model = t.model
+		return &user, t.history
+	}
+
+	history := make([]*domain.Message, 0, len(t.history)+len(t.pending))
+	history = append(history, t.history...)
+	history = append(history, t.user)
+	history = append(history, t.pending[:len(t.pending)-1]...)
+
+	last := *t.pending[len(t.pending)-1]
+	last.Model = t.model
+	last.Options = t.user.Options
+	last.Tools = t.user.Tools
+	return &last, history
+}
+
+// branchHistory returns the branch a new message continues: the one ending at
+// parentID, or the latest message of the chat when parentID is nil.
+func (uc *chatUseCase) branchHistory(ctx context.Context, chatID domain.ChatID, parentID *domain.MessageID) ([]*domain.Message, error) {
+	if parentID == nil {
+		latest, err := uc.chatRepo.GetRecentMessages(ctx, chatID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) == 0 {
+			return nil, nil
+		}
+		parentID = &latest[0].ID
+	} else if _, err := uc.chatRepo.GetMessage(ctx, chatID, *parentID); err != nil {
+		return nil, err
+	}
+
+	return uc.chatRepo.GetAncestors(ctx, chatID, *parentID, uc.config.HistoryLimit)
+}
+
+// prepareMessage saves the user turn and selects the history to send with
+// it, led by the system prompt. Generation options are the chat defaults
+// overridden by the per-message options.
+func (uc *chatUseCase) prepareMessage(ctx context.Context, chatID domain.ChatID, parentID *domain.MessageID, content string, model string, options *domain.GenerationOptions, attachments domain.Attachments, format *domain.ResponseFormat) (*turn, error) {
+	chat, err := uc.chatRepo.GetByID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	if err := uc.checkAttachments(ctx, model, attachments); err != nil {
+		return nil, err
+	}
+
+	// Get the most recent history of the branch, the context builder trims it further
+	messages, err := uc.branchHistory(ctx, chatID, parentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat history: %w", err)
 	}
 
 	// Create and save user message
 	userMessage := domain.NewMessage(chatID, content, domain.UserRole, model)
-	err = uc.chatRepo.AddMessage(ctx, chatID, userMessage)
+	if len(messages) > 0 {
+		userMessage.ParentID = &messages[len(messages)-1].ID
+	}
+	userMessage.Options = chat.Options.Merge(options)
+	for _, a := range attachments {
+		if err := uc.features.Attachments.Save(ctx, chatID, a); err != nil {
+			return nil, fmt.Errorf("failed to save attachment: %w", err)
+		}
+	}
+	userMessage.Attachments = attachments
+	err = uc.saveMessage(ctx, userMessage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save user message: %w", err)
 	}
+	userMessage.Format = format
+
+	// Tools are not offered to models known to reject them
+	if uc.features.Tools != nil {
+		if info := uc.modelInfo(ctx, model); info == nil || info.Supports(domain.CapabilityTools) {
+			userMessage.Tools = uc.features.Tools.Definitions()
+		}
+	}
+
+	return uc.buildTurn(ctx, chat, userMessage, messages)
+}
+
+// buildTurn selects the history to send with the user turn, led by the
+// system prompt. messages is the chat history preceding the turn.
+func (uc *chatUseCase) buildTurn(ctx context.Context, chat *domain.Chat, userMessage *domain.Message, messages []*domain.Message) (*turn, error) {
+	chatID, content, model := userMessage.ChatID, userMessage.Content, userMessage.Model
+
+	// The system prompt is not persisted, it always reflects the current chat settings
+	systemMessage := domain.NewMessage(chatID, uc.systemPrompt(chat), domain.SystemRole, model)
+	pinned := []*domain.Message{systemMessage}
+
+	// Messages folded into the branch's summary are replaced by the summary
+	// itself
+	if uc.features.Summarizer != nil {
+		summary, err := uc.features.Summarizer.Latest(ctx, chatID, messages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chat summary: %w", err)
+		}
+		if summary != nil {
+			pinned = append(pinned, domain.NewMessage(chatID, "Summary of the earlier conversation:\n"+summary.Content, domain.SystemRole, model))
+			messages = unsummarized(summary, messages)
+		}
+	}
+
+	// Retrieval is best effort, a failure should not block the conversation
+	var sources []domain.ChunkSearchResult
+	if uc.features.Retriever != nil {
+		var err error
+		sources, err = uc.features.Retriever.Retrieve(ctx, chatID, content)
+		if err != nil {
+			log.Printf("Failed to retrieve documents for chat %s: %v", uuid.UUID(chatID), err)
+		}
+		if len(sources) > 0 {
+			pinned = append(pinned, uc.features.Retriever.contextMessage(chatID, model, sources))
+		}
+	}
+
+	reserve := uc.config.ResponseReserve
+	if o := userMessage.Options; o != nil && o.NumPredict != nil && *o.NumPredict > 0 {
+		reserve = *o.NumPredict
+	}
+
+	contextLength := uc.contextLength(ctx, model, userMessage.Options)
+	window := uc.contextBuilder.Build(pinned, messages, userMessage, contextLength, reserve)
+	// The provider must run with the window the history was trimmed for,
+	// Ollama would otherwise use its own default and cut the prompt
+	userMessage.Options = userMessage.Options.Merge(&domain.GenerationOptions{NumCtx: &contextLength})
+	if window.Usage.Dropped > 0 {
+		log.Printf("Dropped %d of %d messages from context for chat %s", window.Usage.Dropped, len(messages), uuid.UUID(chatID))
+	}
+
+	// Earlier images are only sent along to models that can see them
+	if uc.features.Attachments != nil && uc.supportsVision(ctx, model) {
+		uc.loadAttachments(ctx, window.Messages)
+		uc.loadAttachments(ctx, []*domain.Message{userMessage})
+	}
+
+	return &turn{
+		user:       userMessage,
+		responseID: domain.MessageID(uuid.New()),
+		history:    window.Messages,
+		usage:      window.Usage,
+		sources:    sources,
+		model:      model,
+		fallbacks:  uc.config.FallbackChains[model],
+	}, nil
+}
+
+// fallBack moves the turn to the next model of the fallback chain when err
+// is a failure another model may not have, such as the model missing or
+// running out of memory. It reports whether there is a model left to try.
+func (uc *chatUseCase) fallBack(t *turn, err error) bool {
+	if len(t.fallbacks) == 0 {
+		return false
+	}
+	if !errors.Is(err, domain.ErrModelNotFound) && !errors.Is(err, domain.ErrModelFailed) && !errors.Is(err, domain.ErrProviderUnavailable) && !errors.Is(err, domain.ErrProviderTimeout) {
+		return false
+	}
 
-	// Add the new user message to the history
-	messages = append(messages, userMessage)
+	if t.fallback == nil {
+		t.fallback = &domain.Fallback{RequestedModel: t.user.Model}
+	}
+	t.fallback.Failures = append(t.fallback.Failures, domain.ModelFailure{Model: t.model, Reason: err.Error()})
 
-	// Get AI response with chat history
-	aiResponse, err := uc.modelService.SendMessage(ctx, userMessage, messages)
+	log.Printf("Model %s failed in chat %s, falling back to %s: %v", t.model, uuid.UUID(t.user.ChatID), t.fallbacks[0], err)
+	t.model = t.fallbacks[0]
+	t.fallbacks = t.fallbacks[1:]
+	return true
+}
+
+// generate sends the current request, walking the fallback chain until a
+// model answers.
+func (uc *chatUseCase) generate(ctx context.Context, t *turn) (*domain.Message, error) {
+	for {
+		request, history := t.request()
+		aiResponse, err := uc.modelService.SendMessage(ctx, request, history)
+		if err == nil {
+			return aiResponse, nil
+		}
+		if ctx.Err() != nil || !uc.fallBack(t, err) {
+			return nil, fmt.Errorf("failed to get AI response: %w", err)
+		}
+	}
+}
+
+// startStream is generate for streamed answers. Only starting the stream
+// falls back, a stream that fails midway is reported as is.
+func (uc *chatUseCase) startStream(ctx context.Context, t *turn) (<-chan domain.MessageChunk, error) {
+	for {
+		request, history := t.request()
+		stream, err := uc.modelService.StreamMessage(ctx, request, history)
+		if err == nil {
+			return stream, nil
+		}
+		if ctx.Err() != nil || !uc.fallBack(t, err) {
+			return nil, fmt.Errorf("failed to start AI response stream: %w", err)
+		}
+	}
+}
+
+// completeTurn records how the answer was produced, saves it and kicks off
+// background work that depends on the new message.
+func (uc *chatUseCase) completeTurn(ctx context.Context, t *turn, aiResponse *domain.Message) error {
+	aiResponse.ID = t.responseID
+	parentID := t.leaf()
+	aiResponse.ParentID = &parentID
+	// Record the options used so the answer can be reproduced
+	aiResponse.Options = t.user.Options
+	aiResponse.Fallback = t.fallback
+	aiResponse.Context = &t.usage
+	aiResponse.CitedChunks = citedChunks(t.sources, aiResponse.Content)
+
+	if err := uc.saveMessage(ctx, aiResponse); err != nil {
+		return fmt.Errorf("failed to save AI response: %w", err)
+	}
+
+	if uc.features.Summarizer != nil {
+		uc.features.Summarizer.Schedule(t.user.ChatID, aiResponse.ID, t.model)
+	}
+	return nil
+}
+
+// cancelledAnswer is the answer recorded for a turn the user cancelled.
+func cancelledAnswer(t *turn, partial string) *domain.Message {
+	log.Printf("Generation cancelled in chat %s after %d characters", uuid.UUID(t.user.ChatID), len(partial))
+
+	aiResponse := domain.NewMessage(t.user.ChatID, partial, domain.AssistantRole, t.model)
+	aiResponse.Status = domain.MessageCancelled
+	return aiResponse
+}
+
+// cancelTurn saves what was generated before the user cancelled the turn.
+func (uc *chatUseCase) cancelTurn(ctx context.Context, t *turn, partial string) (*domain.Message, error) {
+	aiResponse := cancelledAnswer(t, partial)
+	// The generation context is gone, but the partial answer must still be saved
+	if err := uc.completeTurn(context.WithoutCancel(ctx), t, aiResponse); err != nil {
+		return nil, err
+	}
+	return aiResponse, nil
+}
+
+// discardTurn deletes the messages saved for a turn that produced no usable
+// answer, so they do not linger in the history of later turns.
+func (uc *chatUseCase) discardTurn(ctx context.Context, t *turn) {
+	ctx = context.WithoutCancel(ctx)
+	saved := append([]*domain.Message{t.user}, t.pending...)
+	for i := len(saved) - 1; i >= 0; i-- {
+		if err := uc.chatRepo.DeleteMessage(ctx, t.user.ChatID, saved[i].ID); err != nil {
+			log.Printf("Failed to discard message %s: %v", uuid.UUID(saved[i].ID), err)
+		}
+	}
+}
+
+// runTools saves the assistant turn that asked for tools, runs the calls and
+// queues both for the follow-up request. It returns the saved messages.
+func (uc *chatUseCase) runTools(ctx context.Context, t *turn, call *domain.Message) ([]*domain.Message, error) {
+	for i := range call.ToolCalls {
+		if call.ToolCalls[i].ID == "" {
+			call.ToolCalls[i].ID = uuid.NewString()
+		}
+	}
+
+	parentID := t.leaf()
+	call.ParentID = &parentID
+	call.Options = t.user.Options
+	if err := uc.saveMessage(ctx, call); err != nil {
+		return nil, fmt.Errorf("failed to save tool call: %w", err)
+	}
+
+	saved := []*domain.Message{call}
+	for _, toolCall := range call.ToolCalls {
+		result := uc.features.Tools.Execute(ctx, t.user.ChatID, t.model, toolCall)
+		previous := saved[len(saved)-1].ID
+		result.ParentID = &previous
+		if err := uc.saveMessage(ctx, result); err != nil {
+			return nil, fmt.Errorf("failed to save tool result: %w", err)
+		}
+		saved = append(saved, result)
+	}
+
+	t.pending = append(t.pending, saved...)
+	t.toolRounds++
+	// Once the budget is spent the model has to answer with what it has
+	if t.toolRounds >= uc.features.Tools.maxRounds {
+		t.user.Tools = nil
+	}
+	return saved, nil
+}
+
+const formatRetryPrompt = `Your previous answer could not be used: %v.
+Reply again with only the JSON value, following the requested format exactly.`
+
+// enforceFormat parses the answer to a turn that requested a format. An
+// invalid answer is re-prompted once when the format allows it; the invalid
+// attempt is not saved.
+func (uc *chatUseCase) enforceFormat(ctx context.Context, t *turn, aiResponse *domain.Message) (*domain.Message, error) {
+	format := t.user.Format
+	parsed, err := parseStructuredOutput(format, aiResponse.Content)
+	if err != nil && format.Retry {
+		log.Printf("Re-prompting for structured output in chat %s: %v", uuid.UUID(t.user.ChatID), err)
+
+		request, history := t.request()
+		history = append(history[:len(history):len(history)], request, aiResponse)
+		correction := domain.NewMessage(t.user.ChatID, fmt.Sprintf(formatRetryPrompt, err), domain.UserRole, t.model)
+		correction.Options = t.user.Options
+		correction.Format = format
+
+		aiResponse, err = uc.modelService.SendMessage(ctx, correction, history)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get AI response: %w", err)
+		}
+		parsed, err = parseStructuredOutput(format, aiResponse.Content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidStructuredOutput, err)
+	}
+
+	aiResponse.Parsed = parsed
+	return aiResponse, nil
+}
+
+// wantsTools reports whether the model answered with tool calls that may
+// still run. Once the round budget is spent the tools are withdrawn and the
+// model is asked one last time for an answer.
+func (uc *chatUseCase) wantsTools(t *turn, toolCalls domain.ToolCalls) bool {
+	return len(toolCalls) > 0 && uc.features.Tools != nil && len(t.user.Tools) > 0 && t.toolRounds < uc.features.Tools.maxRounds
+}
+
+// finalAnswer drops tool calls that will not run. A model that still only
+// calls tools when asked to answer without them gives no answer at all.
+func finalAnswer(t *turn, aiResponse *domain.Message) error {
+	if len(aiResponse.ToolCalls) == 0 {
+		return nil
+	}
+	aiResponse.ToolCalls = nil
+	if strings.TrimSpace(aiResponse.Content) == "" {
+		return fmt.Errorf("%w: %d rounds of tool calls", domain.ErrToolRoundsExceeded, t.toolRounds)
+	}
+	return nil
+}
+
+// answer gets the AI response with chat history, running tools until the
+// model answers.
+func (uc *chatUseCase) answer(ctx context.Context, t *turn) (*domain.Message, error) {
+	for {
+		aiResponse, err := uc.generate(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		if uc.wantsTools(t, aiResponse.ToolCalls) {
+			if _, err := uc.runTools(ctx, t, aiResponse); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := finalAnswer(t, aiResponse); err != nil {
+			return nil, err
+		}
+
+		if t.user.Format != nil {
+			return uc.enforceFormat(ctx, t, aiResponse)
+		}
+		return aiResponse, nil
+	}
+}
+
+func (uc *chatUseCase) SendMessage(ctx context.Context, chatID domain.ChatID, parentID *domain.MessageID, content string, model string, options *domain.GenerationOptions, attachments domain.Attachments, format *domain.ResponseFormat) (*domain.Message, error) {
+	t, err := uc.prepareMessage(ctx, chatID, parentID, content, model, options, attachments, format)
+	if err != nil {
+		return nil, err
+	}
+
+	generationCtx, gen := uc.generations.start(ctx, t)
+	defer uc.generations.finish(gen)
+
+	aiResponse, err := uc.answer(generationCtx, t)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get AI response: %w", err)
+		// A non-streamed answer has nothing partial to keep
+		if cancelled(generationCtx) {
+			return uc.cancelTurn(ctx, t, "")
+		}
+		if errors.Is(err, domain.ErrInvalidStructuredOutput) {
+			uc.discardTurn(ctx, t)
+		}
+		return nil, err
 	}
 
 	// Save AI response
-	err = uc.chatRepo.AddMessage(ctx, chatID, aiResponse)
+	if err := uc.completeTurn(ctx, t, aiResponse); err != nil {
+		return nil, err
+	}
+
+	return aiResponse, nil
+}
+
+func (uc *chatUseCase) StreamMessage(ctx context.Context, chatID domain.ChatID, parentID *domain.MessageID, content string, model string, options *domain.GenerationOptions, attachments domain.Attachments, format *domain.ResponseFormat) (<-chan domain.MessageChunk, error) {
+	t, err := uc.prepareMessage(ctx, chatID, parentID, content, model, options, attachments, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// The model runs under its own context so it can be cancelled while the
+	// client stays connected to receive the partial answer
+	generationCtx, gen := uc.generations.start(ctx, t)
+	stream, err := uc.startStream(generationCtx, t)
+	if err != nil {
+		uc.generations.finish(gen)
+		if cancelled(generationCtx) {
+			return nil, domain.ErrGenerationCancelled
+		}
+		return nil, err
+	}
+
+	chunks := make(chan domain.MessageChunk)
+	go func() {
+		defer close(chunks)
+		defer uc.generations.finish(gen)
+
+		send := func(chunk domain.MessageChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// fail ends the stream with err, or with the partial answer when the
+		// user cancelled the generation
+		fail := func(err error, partial string) {
+			if !cancelled(generationCtx) {
+				send(domain.MessageChunk{Err: err})
+				return
+			}
+			aiResponse, err := uc.cancelTurn(ctx, t, partial)
+			if err != nil {
+				send(domain.MessageChunk{Err: err})
+				return
+			}
+			send(domain.MessageChunk{Done: true, DoneReason: string(domain.MessageCancelled), Message: aiResponse})
+		}
+
+		if !send(domain.MessageChunk{Started: &domain.GenerationStarted{UserMessageID: t.user.ID, MessageID: t.responseID}}) {
+			return
+		}
+
+	generation:
+		for {
+			var response strings.Builder
+			var done *domain.MessageChunk
+			for chunk := range stream {
+				if chunk.Err != nil {
+					// Nothing reached the client yet, so another model can
+					// still answer in its place
+					if response.Len() == 0 && generationCtx.Err() == nil && uc.fallBack(t, chunk.Err) {
+						var err error
+						if stream, err = uc.startStream(generationCtx, t); err != nil {
+							fail(err, "")
+							return
+						}
+						continue generation
+					}
+					fail(fmt.Errorf("failed to get AI response: %w", chunk.Err), response.String())
+					return
+				}
+				response.WriteString(chunk.Content)
+				if chunk.Done {
+					done = &chunk
+					break
+				}
+				if !send(chunk) {
+					return
+				}
+			}
+			if done == nil {
+				// The provider closes the stream without an error when its
+				// context ends
+				if cancelled(generationCtx) {
+					fail(domain.ErrGenerationCancelled, response.String())
+				}
+				return
+			}
+
+			aiResponse := domain.NewMessage(chatID, response.String(), domain.AssistantRole, t.model)
+			aiResponse.Provider = done.Provider
+			aiResponse.Stats = done.Stats
+			aiResponse.Cached = done.Cached
+			aiResponse.ToolCalls = done.ToolCalls
+
+			// Tool calls and results are streamed as whole messages before
+			// the model is asked to continue
+			if uc.wantsTools(t, done.ToolCalls) {
+				saved, err := uc.runTools(generationCtx, t, aiResponse)
+				if err != nil {
+					fail(err, "")
+					return
+				}
+				for _, message := range saved {
+					if !send(domain.MessageChunk{Message: message}) {
+						return
+					}
+				}
+
+				if stream, err = uc.startStream(generationCtx, t); err != nil {
+					fail(err, "")
+					return
+				}
+				continue
+			}
+			if err := finalAnswer(t, aiResponse); err != nil {
+				fail(err, "")
+				return
+			}
+			done.ToolCalls = nil
+
+			// A re-prompted answer is not streamed, the done message carries it
+			if t.user.Format != nil {
+				formatted, err := uc.enforceFormat(generationCtx, t, aiResponse)
+				if err != nil {
+					if errors.Is(err, domain.ErrInvalidStructuredOutput) {
+						uc.discardTurn(ctx, t)
+					}
+					fail(err, aiResponse.Content)
+					return
+				}
+				aiResponse = formatted
+			}
+
+			// Persist the assembled answer once the model is done
+			if err := uc.completeTurn(ctx, t, aiResponse); err != nil {
+				done.Err = err
+			} else {
+				done.Message = aiResponse
+			}
+			send(*done)
+			return
+		}
+	}()
+
+	return chunks, nil
+}
+
+// CompareModels answers one user message with each of the models, sending
+// every model the same history. The first model that answers gives the turn
+// its answer, the other answers are saved as inactive alternatives of it.
+// The user message is saved first so that, like any other turn, it can be
+// cancelled by its ID while the models run; it is removed again when no
+// model answers.
+func (uc *chatUseCase) CompareModels(ctx context.Context, chatID domain.ChatID, parentID *domain.MessageID, content string, models []string, options *domain.GenerationOptions) (*domain.Comparison, error) {
+	if len(models) == 0 {
+		return nil, errors.New("at least one model is required")
+	}
+
+	// The context is built for the first model so all of them get the same
+	// history
+	t, err := uc.prepareMessage(ctx, chatID, parentID, content, models[0], options, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Tool rounds would make the answers depend on more than the model
+	t.user.Tools = nil
+
+	generationCtx, gen := uc.generations.start(ctx, t)
+	defer uc.generations.finish(gen)
+
+	turns := make([]*turn, len(models))
+	results := make([]domain.ComparisonResult, len(models))
+	errs := make([]error, len(models))
+	sem := make(chan struct{}, uc.config.CompareParallelism)
+	var wg sync.WaitGroup
+	for i, model := range models {
+		// Each model answers for itself, falling back would defeat the comparison
+		modelTurn := *t
+		modelTurn.model = model
+		modelTurn.fallbacks = nil
+		modelTurn.responseID = domain.MessageID(uuid.New())
+		turns[i] = &modelTurn
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			started := time.Now()
+			aiResponse, err := uc.generate(generationCtx, turns[i])
+			results[i] = domain.ComparisonResult{Model: turns[i].model, Message: aiResponse, LatencyMs: time.Since(started).Milliseconds()}
+			if err != nil {
+				log.Printf("Model %s failed to answer comparison in chat %s: %v", turns[i].model, uuid.UUID(chatID), err)
+				results[i].Error = err.Error()
+				errs[i] = err
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	answered := false
+	for _, result := range results {
+		answered = answered || result.Message != nil
+	}
+	if !answered {
+		uc.discardTurn(ctx, t)
+		if cancelled(generationCtx) {
+			return nil, domain.ErrGenerationCancelled
+		}
+		return nil, fmt.Errorf("no model answered: %w", errs[0])
+	}
+
+	// The answers are kept even if the client went away meanwhile
+	saveCtx := context.WithoutCancel(ctx)
+
+	// Answers are saved in the requested order so the first success stays
+	// the turn's answer
+	var primary *domain.Message
+	for i := range results {
+		aiResponse := results[i].Message
+		if aiResponse == nil {
+			continue
+		}
+		if primary != nil {
+			aiResponse.AlternativeOf = &primary.ID
+			aiResponse.Active = false
+		}
+		if err := uc.completeTurn(saveCtx, turns[i], aiResponse); err != nil {
+			return nil, err
+		}
+		if primary == nil {
+			primary = aiResponse
+		}
+	}
+
+	return &domain.Comparison{UserMessage: t.user, Results: results}, nil
+}
+
+// originalAnswer returns the answer that messageID is, or is an alternative
+// of.
+func (uc *chatUseCase) originalAnswer(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) (*domain.Message, error) {
+	message, err := uc.chatRepo.GetMessage(ctx, chatID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	// Turns that only asked for tools are not answers of their own
+	if message.Role != domain.AssistantRole || len(message.ToolCalls) > 0 {
+		return nil, domain.ErrNotAnAnswer
+	}
+	if message.AlternativeOf == nil {
+		return message, nil
+	}
+
+	original, err := uc.chatRepo.GetMessage(ctx, chatID, *message.AlternativeOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get original answer: %w", err)
+	}
+	return original, nil
+}
+
+// prepareRegeneration rebuilds the turn that original answered: the user
+// message, the tool calls and results that followed it, and the history
+// that preceded it.
+func (uc *chatUseCase) prepareRegeneration(ctx context.Context, original *domain.Message, model string, options *domain.GenerationOptions) (*turn, error) {
+	chat, err := uc.chatRepo.GetByID(ctx, original.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	if original.ParentID == nil {
+		return nil, fmt.Errorf("no user message precedes answer %s", uuid.UUID(original.ID))
+	}
+	messages, err := uc.chatRepo.GetAncestors(ctx, original.ChatID, *original.ParentID, uc.config.HistoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat history: %w", err)
+	}
+
+	userIndex := len(messages) - 1
+	for userIndex >= 0 && messages[userIndex].Role != domain.UserRole {
+		userIndex--
+	}
+	if userIndex < 0 {
+		return nil, fmt.Errorf("no user message precedes answer %s", uuid.UUID(original.ID))
+	}
+
+	// The stored user message is left untouched, only this turn uses the
+	// new model and options
+	userMessage := *messages[userIndex]
+	if model != "" {
+		userMessage.Model = model
+	} else {
+		userMessage.Model = original.Model
+	}
+	userMessage.Options = userMessage.Options.Merge(options)
+
+	t, err := uc.buildTurn(ctx, chat, &userMessage, messages[:userIndex])
+	if err != nil {
+		return nil, err
+	}
+	t.pending = messages[userIndex+1:]
+	return t, nil
+}
+
+// RegenerateMessage answers the turn of messageID again and makes the new
+// answer the active alternative. Tools are not offered again, the model sees
+// the results of the tools the turn already ran. A cancelled regeneration is
+// recorded as an inactive alternative, leaving the current answer in place.
+func (uc *chatUseCase) RegenerateMessage(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID, model string, options *domain.GenerationOptions) (*domain.Message, error) {
+	original, err := uc.originalAnswer(ctx, chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := uc.prepareRegeneration(ctx, original, model, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save AI response: %w", err)
+		return nil, err
+	}
+
+	generationCtx, gen := uc.generations.start(ctx, t)
+	defer uc.generations.finish(gen)
+
+	saveCtx := ctx
+	aiResponse, err := uc.generate(generationCtx, t)
+	if err != nil {
+		if !cancelled(generationCtx) {
+			return nil, err
+		}
+		aiResponse = cancelledAnswer(t, "")
+		aiResponse.Active = false
+		saveCtx = context.WithoutCancel(ctx)
 	}
 
+	// Saving the alternative active deactivates the previous answer; the
+	// repository orders it at the original's place in the chat
+	aiResponse.AlternativeOf = &original.ID
+	if err := uc.completeTurn(saveCtx, t, aiResponse); err != nil {
+		return nil, err
+	}
 	return aiResponse, nil
 }
 
+// ListAlternatives returns the answer of the turn of messageID followed by
+// its alternatives.
+func (uc *chatUseCase) ListAlternatives(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) ([]*domain.Message, error) {
+	original, err := uc.originalAnswer(ctx, chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.chatRepo.GetAlternatives(ctx, chatID, original.ID)
+}
+
+// SelectAlternative makes messageID the answer sent as context for its turn.
+func (uc *chatUseCase) SelectAlternative(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) (*domain.Message, error) {
+	original, err := uc.originalAnswer(ctx, chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.chatRepo.SetActiveAlternative(ctx, chatID, original.ID, messageID); err != nil {
+		return nil, fmt.Errorf("failed to activate alternative: %w", err)
+	}
+
+	selected, err := uc.chatRepo.GetMessage(ctx, chatID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return selected, nil
+}
+
+// EditMessage replaces the content of a message. Later answers are not
+// regenerated, the edit only changes what is sent as context from now on.
+func (uc *chatUseCase) EditMessage(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID, content string) (*domain.Message, error) {
+	if err := uc.chatRepo.EditMessage(ctx, chatID, messageID, content, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to edit message: %w", err)
+	}
+
+	message, err := uc.chatRepo.GetMessage(ctx, chatID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	// The embedding has to follow the new content
+	if uc.features.Indexer != nil {
+		uc.features.Indexer.Index(message)
+	}
+	return message, nil
+}
+
+// DeleteMessage removes a message from the chat. Its replies stay in place
+// and continue from its parent instead.
+func (uc *chatUseCase) DeleteMessage(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) error {
+	if err := uc.chatRepo.DeleteMessage(ctx, chatID, messageID); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	return nil
+}
+
+// ListMessageEdits returns the earlier contents of a message, oldest first.
+// The history of a deleted message ends with its content at deletion.
+func (uc *chatUseCase) ListMessageEdits(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) ([]domain.MessageEdit, error) {
+	edits, err := uc.chatRepo.GetMessageEdits(ctx, chatID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message edits: %w", err)
+	}
+	if len(edits) == 0 {
+		if _, err := uc.chatRepo.GetMessage(ctx, chatID, messageID); err != nil {
+			return nil, fmt.Errorf("failed to get message: %w", err)
+		}
+	}
+	return edits, nil
+}
+
+// GetChatTree returns the first messages of the chat with everything that
+// continues from them.
+func (uc *chatUseCase) GetChatTree(ctx context.Context, chatID domain.ChatID) ([]*domain.MessageNode, error) {
+	roots, _, err := uc.messageTree(ctx, chatID)
+	return roots, err
+}
+
+// ListBranches returns the branches of the chat, most recently extended
+// first. Inactive alternatives do not start a branch of their own.
+func (uc *chatUseCase) ListBranches(ctx context.Context, chatID domain.ChatID) ([]domain.Branch, error) {
+	_, nodes, err := uc.messageTree(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	branches := []domain.Branch{}
+	depths := make(map[domain.MessageID]int, len(nodes))
+	// nodes is in chronological order, so parents come before children
+	for _, node := range nodes {
+		depth := 1
+		if node.ParentID != nil {
+			depth = depths[*node.ParentID] + 1
+		}
+		depths[node.ID] = depth
+
+		if len(node.Children) == 0 && node.Active {
+			branches = append(branches, domain.Branch{Leaf: node.Message, Length: depth})
+		}
+	}
+
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].Leaf.CreatedAt.After(branches[j].Leaf.CreatedAt)
+	})
+	return branches, nil
+}
+
+// messageTree links every message of the chat to its parent. It returns the
+// roots and all nodes in chronological order.
+func (uc *chatUseCase) messageTree(ctx context.Context, chatID domain.ChatID) ([]*domain.MessageNode, []*domain.MessageNode, error) {
+	if _, err := uc.chatRepo.GetByID(ctx, chatID); err != nil {
+		return nil, nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	messages, err := uc.chatRepo.GetAllMessages(ctx, chatID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get chat messages: %w", err)
+	}
+
+	nodes := make([]*domain.MessageNode, len(messages))
+	byID := make(map[domain.MessageID]*domain.MessageNode, len(messages))
+	for i, m := range messages {
+		nodes[i] = &domain.MessageNode{Message: m, Children: []*domain.MessageNode{}}
+		byID[m.ID] = nodes[i]
+	}
+
+	roots := []*domain.MessageNode{}
+	for _, node := range nodes {
+		if node.ParentID != nil {
+			if parent, ok := byID[*node.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nodes, nil
+}
+
+// CancelGeneration stops the answer being generated for the user message
+// or answer messageID. The request that started the generation saves the
+// partial answer as cancelled.
+func (uc *chatUseCase) CancelGeneration(ctx context.Context, chatID domain.ChatID, messageID domain.MessageID) error {
+	if !uc.generations.cancel(chatID, messageID) {
+		return domain.ErrGenerationNotFound
+	}
+	return nil
+}
+
 func (uc *chatUseCase) GetChatHistory(ctx context.Context, chatID domain.ChatID, limit, offset int) ([]*domain.Message, error) {
 	return uc.chatRepo.GetMessages(ctx, chatID, limit, offset)
 }
 
 func (uc *chatUseCase) ListAvailableModels(ctx context.Context) ([]string, error) {
-	return uc.modelService.ListAvailableModels(ctx)
+	models, err := uc.modelService.ListModels(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(models))
+	for i, model := range models {
+		names[i] = model.Name
+	}
+	return names, nil
+}
+
+func (uc *chatUseCase) ListModels(ctx context.Context) ([]domain.ModelInfo, error) {
+	return uc.modelService.ListModels(ctx, true)
 }
 
-func (uc *chatUseCase) UpdateChat(ctx context.Context, id domain.ChatID, title string) (*domain.Chat, error) {
+func (uc *chatUseCase) GetModelInfo(ctx context.Context, name string) (*domain.ModelInfo, error) {
+	return uc.modelService.GetModelInfo(ctx, name)
+}
+
+func (uc *chatUseCase) UpdateChat(ctx context.Context, id domain.ChatID, title string, systemPrompt *string, options *domain.GenerationOptions) (*domain.Chat, error) {
 	chat, err := uc.chatRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	chat.Title = title
+	// System prompt and options are only replaced when provided
+	if systemPrompt != nil {
+		chat.SystemPrompt = *systemPrompt
+	}
+	if options != nil {
+		chat.Options = options
+	}
 	chat.UpdatedAt = time.Now()
 
 	err = uc.chatRepo.Update(ctx, chat)
@@ -0,0 +1,63 @@
+package usecases
+
+import (
+	"strings"
+)
+
+// chunkText splits text into chunks of at most size runes. Paragraphs are
+// kept together where possible; longer paragraphs are cut with overlap runes
+// repeated between consecutive pieces so sentences spanning a cut remain
+// retrievable.
+func chunkText(text string, size, overlap int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentLen := 0
+
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		currentLen = 0
+	}
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		runes := []rune(paragraph)
+		if len(runes) > size {
+			flush()
+			step := size - overlap
+			if step <= 0 {
+				step = size
+			}
+			for start := 0; start < len(runes); start += step {
+				end := start + size
+				if end > len(runes) {
+					end = len(runes)
+				}
+				chunks = append(chunks, strings.TrimSpace(string(runes[start:end])))
+				if end == len(runes) {
+					break
+				}
+			}
+			continue
+		}
+
+		if currentLen > 0 && currentLen+2+len(runes) > size {
+			flush()
+		}
+		if currentLen > 0 {
+			current.WriteString("\n\n")
+			currentLen += 2
+		}
+		current.WriteString(paragraph)
+		currentLen += len(runes)
+	}
+	flush()
+
+	return chunks
+}
@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// DefaultSystemPrompt is used when neither the chat nor the server
+// configuration provides a system prompt.
+const DefaultSystemPrompt = `You are a helpful AI assistant. Please follow these guidelines:
+				- Use clear and concise language
+				- When sharing code, use proper markdown formatting:
+				- Inline code with single backticks: ` + "`code`" + `
+				- Code blocks with triple backticks and language: ` + "```language" + `
+				- Provide context-aware responses
+				- Maintain consistency in formatting
+				- Never use HTML tags for code formatting
+				- Always validate inputs and provide appropriate error messages`
+
+const (
+	defaultContextLength   = 4096
+	defaultMaxContext      = 8192
+	defaultResponseReserve = 512
+	defaultHistoryLimit    = 200
+	defaultCompareParallel = 2
+)
+
+type ChatConfig struct {
+	// DefaultSystemPrompt applies to chats without their own system prompt
+	DefaultSystemPrompt string
+	// DefaultContextLength is used when the model does not report one
+	DefaultContextLength int
+	// MaxContextLength caps the context length a model reports, since the
+	// provider reserves memory for the whole window
+	MaxContextLength int
+	// ResponseReserve is the number of tokens kept free for the answer when
+	// the request does not set num_predict
+	ResponseReserve int
+	// HistoryLimit caps how many recent messages are considered for context
+	HistoryLimit int
+	// FallbackChains maps a model to the models tried, in order, when it
+	// cannot answer
+	FallbackChains map[string][]string
+	// CompareParallelism bounds how many models answer a comparison at once
+	CompareParallelism int
+}
+
+// ParseFallbackChains parses "a->b->c,d->e". Every model of a chain falls
+// back to the models after it unless a chain of its own says otherwise.
+func ParseFallbackChains(spec string) (map[string][]string, error) {
+	chains := make(map[string][]string)
+	implied := make(map[string][]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		models := strings.Split(entry, "->")
+		for i := range models {
+			models[i] = strings.TrimSpace(models[i])
+			if models[i] == "" {
+				return nil, fmt.Errorf("invalid fallback chain %q, expected model->model", entry)
+			}
+		}
+		if len(models) < 2 {
+			return nil, fmt.Errorf("invalid fallback chain %q, expected model->model", entry)
+		}
+
+		if _, exists := chains[models[0]]; exists {
+			return nil, fmt.Errorf("model %q has more than one fallback chain", models[0])
+		}
+		chains[models[0]] = models[1:]
+		for i := 1; i < len(models)-1; i++ {
+			if _, exists := implied[models[i]]; !exists {
+				implied[models[i]] = models[i+1:]
+			}
+		}
+	}
+
+	for model, fallbacks := range implied {
+		if _, exists := chains[model]; !exists {
+			chains[model] = fallbacks
+		}
+	}
+	return chains, nil
+}
+
+func (c *ChatConfig) applyDefaults() {
+	if c.DefaultSystemPrompt == "" {
+		c.DefaultSystemPrompt = DefaultSystemPrompt
+	}
+	if c.DefaultContextLength <= 0 {
+		c.DefaultContextLength = defaultContextLength
+	}
+	if c.MaxContextLength <= 0 {
+		c.MaxContextLength = defaultMaxContext
+	}
+	if c.ResponseReserve <= 0 {
+		c.ResponseReserve = defaultResponseReserve
+	}
+	if c.HistoryLimit <= 0 {
+		c.HistoryLimit = defaultHistoryLimit
+	}
+	if c.CompareParallelism <= 0 {
+		c.CompareParallelism = defaultCompareParallel
+	}
+}
+
+// ChatFeatures are the optional subsystems of the chat use case; a nil field
+// disables the feature.
+type ChatFeatures struct {
+	Summarizer *Summarizer
+	Indexer    *MessageIndexer
+	Retriever  *Retriever
+	Tools      *ToolRegistry
+	// Attachments stores uploaded images; without it uploads are rejected
+	Attachments ports.AttachmentStore
+}
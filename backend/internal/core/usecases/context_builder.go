@@ -0,0 +1,107 @@
+package usecases
+
+import (
+	"math"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+// TokenEstimator approximates how many tokens a model needs for a text.
+type TokenEstimator interface {
+	EstimateTokens(text string) int
+}
+
+// charTokenEstimator assumes a fixed number of characters per token, which
+// is close enough for budgeting across the models we run without shipping
+// every model's tokenizer.
+type charTokenEstimator struct {
+	charsPerToken float64
+}
+
+func NewCharTokenEstimator(charsPerToken float64) TokenEstimator {
+	if charsPerToken <= 0 {
+		charsPerToken = 4
+	}
+	return &charTokenEstimator{charsPerToken: charsPerToken}
+}
+
+func (e *charTokenEstimator) EstimateTokens(text string) int {
+	runes := len([]rune(text))
+	if runes == 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(runes) / e.charsPerToken))
+}
+
+// messageOverhead accounts for the role markers and separators chat
+// templates wrap around every message.
+const messageOverhead = 4
+
+// ContextWindow is the history selected for a generation.
+type ContextWindow struct {
+	// Messages holds the pinned messages followed by the selected history in
+	// chronological order. The new user turn is not included.
+	Messages []*domain.Message
+	Usage    domain.ContextUsage
+}
+
+type ContextBuilder struct {
+	estimator TokenEstimator
+}
+
+func NewContextBuilder(estimator TokenEstimator) *ContextBuilder {
+	return &ContextBuilder{estimator: estimator}
+}
+
+func (b *ContextBuilder) estimate(msg *domain.Message) int {
+	tokens := b.estimator.EstimateTokens(msg.Content) + messageOverhead
+	for _, call := range msg.ToolCalls {
+		tokens += b.estimator.EstimateTokens(call.Name + string(call.Arguments))
+	}
+	return tokens
+}
+
+// Build selects the most recent messages of history that fit in
+// contextLength once the pinned messages (system prompt, summary), the new
+// user turn and reserve tokens for the answer are accounted for. Pinned
+// messages and the user turn are always kept, even if they alone exceed the
+// budget. history must be in chronological order.
+func (b *ContextBuilder) Build(pinned []*domain.Message, history []*domain.Message, user *domain.Message, contextLength, reserve int) ContextWindow {
+	budget := contextLength - reserve
+	used := b.estimate(user)
+	for _, m := range pinned {
+		used += b.estimate(m)
+	}
+
+	// Walk backwards from the newest message until the budget runs out
+	start := len(history)
+	for start > 0 {
+		cost := b.estimate(history[start-1])
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start--
+	}
+
+	// A tool result is meaningless without the call it answers, and some
+	// providers reject it outright
+	for start < len(history) && history[start].Role == domain.ToolRole {
+		used -= b.estimate(history[start])
+		start++
+	}
+
+	messages := make([]*domain.Message, 0, len(pinned)+len(history)-start)
+	messages = append(messages, pinned...)
+	messages = append(messages, history[start:]...)
+
+	return ContextWindow{
+		Messages: messages,
+		Usage: domain.ContextUsage{
+			ContextLength:   contextLength,
+			EstimatedTokens: used,
+			Included:        len(history) - start,
+			Dropped:         start,
+		},
+	}
+}
@@ -0,0 +1,148 @@
+package usecases
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+func TestCharTokenEstimator(t *testing.T) {
+	tests := []struct {
+		charsPerToken float64
+		text          string
+		want          int
+	}{
+		{4, "", 0},
+		{4, "abc", 1},
+		{4, "abcde", 2},
+		// Runes count, not bytes
+		{4, "äöüß", 1},
+		// A non-positive ratio falls back to four characters per token
+		{0, "abcdefgh", 2},
+	}
+	for _, tt := range tests {
+		if got := NewCharTokenEstimator(tt.charsPerToken).EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) at %v = %d, want %d", tt.text, tt.charsPerToken, got, tt.want)
+		}
+	}
+}
+
+// contextMessage costs size tokens plus the message overhead with one
+// character per token.
+func contextMessage(role domain.MessageRole, size int) *domain.Message {
+	return domain.NewMessage(domain.ChatID(uuid.New()), strings.Repeat("x", size), role, "llama3")
+}
+
+func TestContextBuilderBuild(t *testing.T) {
+	system := contextMessage(domain.SystemRole, 6)
+	summary := contextMessage(domain.SystemRole, 6)
+	user := contextMessage(domain.UserRole, 6)
+
+	history := []*domain.Message{
+		contextMessage(domain.UserRole, 6),
+		contextMessage(domain.AssistantRole, 6),
+		contextMessage(domain.UserRole, 6),
+		contextMessage(domain.AssistantRole, 6),
+	}
+
+	call := contextMessage(domain.AssistantRole, 0)
+	call.ToolCalls = domain.ToolCalls{{Name: "calc", Arguments: json.RawMessage(`{}`)}}
+	toolTurn := []*domain.Message{
+		contextMessage(domain.UserRole, 6),
+		call,
+		contextMessage(domain.ToolRole, 6),
+		contextMessage(domain.AssistantRole, 6),
+	}
+
+	tests := []struct {
+		name          string
+		pinned        []*domain.Message
+		history       []*domain.Message
+		contextLength int
+		reserve       int
+		want          []*domain.Message
+		wantTokens    int
+	}{
+		{
+			name:          "everything fits",
+			pinned:        []*domain.Message{system},
+			history:       history,
+			contextLength: 100,
+			want:          append([]*domain.Message{system}, history...),
+			wantTokens:    60,
+		},
+		{
+			name:          "the oldest messages are dropped",
+			pinned:        []*domain.Message{system},
+			history:       history,
+			contextLength: 60,
+			reserve:       10,
+			want:          []*domain.Message{system, history[1], history[2], history[3]},
+			wantTokens:    50,
+		},
+		{
+			name:          "the summary is kept after the system prompt",
+			pinned:        []*domain.Message{system, summary},
+			history:       history,
+			contextLength: 60,
+			reserve:       10,
+			want:          []*domain.Message{system, summary, history[2], history[3]},
+			wantTokens:    50,
+		},
+		{
+			name:          "pinned messages stay when they alone exceed the budget",
+			pinned:        []*domain.Message{system, summary},
+			history:       history,
+			contextLength: 20,
+			reserve:       5,
+			want:          []*domain.Message{system, summary},
+			wantTokens:    30,
+		},
+		{
+			name:          "a tool result is not kept without its call",
+			pinned:        []*domain.Message{system},
+			history:       toolTurn,
+			contextLength: 40,
+			want:          []*domain.Message{system, toolTurn[3]},
+			wantTokens:    30,
+		},
+		{
+			name:          "a tool call keeps its result",
+			pinned:        []*domain.Message{system},
+			history:       toolTurn,
+			contextLength: 50,
+			want:          []*domain.Message{system, call, toolTurn[2], toolTurn[3]},
+			wantTokens:    50,
+		},
+	}
+
+	builder := NewContextBuilder(NewCharTokenEstimator(1))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := builder.Build(tt.pinned, tt.history, user, tt.contextLength, tt.reserve)
+
+			if len(window.Messages) != len(tt.want) {
+				t.Fatalf("got %d messages, want %d", len(window.Messages), len(tt.want))
+			}
+			for i := range tt.want {
+				if window.Messages[i] != tt.want[i] {
+					t.Errorf("message %d is not the expected one", i)
+				}
+			}
+
+			included := len(tt.want) - len(tt.pinned)
+			want := domain.ContextUsage{
+				ContextLength:   tt.contextLength,
+				EstimatedTokens: tt.wantTokens,
+				Included:        included,
+				Dropped:         len(tt.history) - included,
+			}
+			if window.Usage != want {
+				t.Errorf("usage = %+v, want %+v", window.Usage, want)
+			}
+		})
+	}
+}
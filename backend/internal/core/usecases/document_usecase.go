@@ -0,0 +1,147 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+const (
+	documentChunkSize    = 1000
+	documentChunkOverlap = 150
+	// embedBatchSize bounds how many chunks are sent in one embedding request
+	embedBatchSize = 32
+)
+
+// supportedContentTypes are accepted as uploads. PDFs have to be converted
+// to text by the client first.
+var supportedContentTypes = map[string]bool{
+	"text/plain":      true,
+	"text/markdown":   true,
+	"text/x-markdown": true,
+}
+
+type documentUseCase struct {
+	documentRepo ports.DocumentRepository
+	embeddings   ports.EmbeddingService
+}
+
+func NewDocumentUseCase(documentRepo ports.DocumentRepository, embeddings ports.EmbeddingService) ports.DocumentUseCase {
+	return &documentUseCase{
+		documentRepo: documentRepo,
+		embeddings:   embeddings,
+	}
+}
+
+func (uc *documentUseCase) UploadDocument(ctx context.Context, chatID *domain.ChatID, title string, contentType string, content []byte) (*domain.Document, error) {
+	if !supportedContentTypes[contentType] || !utf8.Valid(content) {
+		return nil, domain.ErrUnsupportedDocument
+	}
+
+	pieces := chunkText(string(content), documentChunkSize, documentChunkOverlap)
+	if len(pieces) == 0 {
+		return nil, domain.ErrEmptyDocument
+	}
+
+	document := domain.NewDocument(chatID, title, contentType, len(content))
+	document.ChunkCount = len(pieces)
+
+	chunks := make([]*domain.DocumentChunk, len(pieces))
+	for start := 0; start < len(pieces); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(pieces) {
+			end = len(pieces)
+		}
+
+		vectors, err := uc.embeddings.Embed(ctx, pieces[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed document: %w", err)
+		}
+		for i, vector := range vectors {
+			chunk := domain.NewDocumentChunk(document.ID, start+i, pieces[start+i])
+			chunk.Embedding = vector
+			chunks[start+i] = chunk
+		}
+	}
+
+	if err := uc.documentRepo.CreateDocument(ctx, document, chunks, uc.embeddings.Model()); err != nil {
+		return nil, fmt.Errorf("failed to save document: %w", err)
+	}
+	return document, nil
+}
+
+func (uc *documentUseCase) ListDocuments(ctx context.Context, chatID *domain.ChatID) ([]*domain.Document, error) {
+	return uc.documentRepo.ListDocuments(ctx, chatID)
+}
+
+func (uc *documentUseCase) DeleteDocument(ctx context.Context, id uuid.UUID) error {
+	return uc.documentRepo.DeleteDocument(ctx, id)
+}
+
+// Retriever finds the document chunks relevant to a user turn.
+type Retriever struct {
+	documentRepo ports.DocumentRepository
+	embeddings   ports.EmbeddingService
+	topK         int
+}
+
+func NewRetriever(documentRepo ports.DocumentRepository, embeddings ports.EmbeddingService, topK int) *Retriever {
+	if topK <= 0 {
+		topK = 4
+	}
+	return &Retriever{
+		documentRepo: documentRepo,
+		embeddings:   embeddings,
+		topK:         topK,
+	}
+}
+
+func (r *Retriever) Retrieve(ctx context.Context, chatID domain.ChatID, query string) ([]domain.ChunkSearchResult, error) {
+	vectors, err := r.embeddings.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return r.documentRepo.SearchChunks(ctx, chatID, r.embeddings.Model(), vectors[0], r.topK)
+}
+
+// contextMessage renders the retrieved chunks as numbered sources the model
+// is asked to cite as [n].
+func (r *Retriever) contextMessage(chatID domain.ChatID, model string, results []domain.ChunkSearchResult) *domain.Message {
+	var b strings.Builder
+	b.WriteString("Answer using the following document excerpts when they are relevant. ")
+	b.WriteString("Cite the excerpts you use with their number in square brackets, e.g. [1].\n\n")
+	for i, result := range results {
+		fmt.Fprintf(&b, "[%d] %s (part %d)\n%s\n\n", i+1, result.DocumentTitle, result.Chunk.Index+1, result.Chunk.Content)
+	}
+	return domain.NewMessage(chatID, strings.TrimSpace(b.String()), domain.SystemRole, model)
+}
+
+// citedChunks returns the chunks referenced as [n] in the answer. If the
+// model did not cite anything explicitly, every provided chunk is returned
+// since all of them were part of the prompt.
+func citedChunks(results []domain.ChunkSearchResult, answer string) domain.ChunkIDs {
+	if len(results) == 0 {
+		return nil
+	}
+
+	var cited domain.ChunkIDs
+	for i, result := range results {
+		if strings.Contains(answer, fmt.Sprintf("[%d]", i+1)) {
+			cited = append(cited, result.Chunk.ID)
+		}
+	}
+	if len(cited) > 0 {
+		return cited
+	}
+
+	all := make(domain.ChunkIDs, len(results))
+	for i, result := range results {
+		all[i] = result.Chunk.ID
+	}
+	return all
+}
@@ -0,0 +1,84 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+// generation is an answer being produced for a user turn.
+type generation struct {
+	chatID     domain.ChatID
+	userID     domain.MessageID
+	responseID domain.MessageID
+	cancel     context.CancelCauseFunc
+}
+
+// generationTracker keeps the in-flight generations of every chat so that
+// another request can cancel them.
+type generationTracker struct {
+	mu     sync.Mutex
+	byChat map[domain.ChatID][]*generation
+}
+
+func newGenerationTracker() *generationTracker {
+	return &generationTracker{byChat: make(map[domain.ChatID][]*generation)}
+}
+
+// start registers the generation answering t and returns the context it has
+// to run under. finish must be called once it ends.
+func (g *generationTracker) start(ctx context.Context, t *turn) (context.Context, *generation) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	gen := &generation{
+		chatID:     t.user.ChatID,
+		userID:     t.user.ID,
+		responseID: t.responseID,
+		cancel:     cancel,
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.byChat[gen.chatID] = append(g.byChat[gen.chatID], gen)
+	return ctx, gen
+}
+
+func (g *generationTracker) finish(gen *generation) {
+	gen.cancel(nil)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	running := g.byChat[gen.chatID]
+	for i, other := range running {
+		if other == gen {
+			running = append(running[:i], running[i+1:]...)
+			break
+		}
+	}
+	if len(running) == 0 {
+		delete(g.byChat, gen.chatID)
+	} else {
+		g.byChat[gen.chatID] = running
+	}
+}
+
+// cancel stops the generation of the chat identified by either its user
+// message or its answer. It reports whether one was running.
+func (g *generationTracker) cancel(chatID domain.ChatID, messageID domain.MessageID) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, gen := range g.byChat[chatID] {
+		if gen.userID == messageID || gen.responseID == messageID {
+			gen.cancel(domain.ErrGenerationCancelled)
+			return true
+		}
+	}
+	return false
+}
+
+// cancelled reports whether ctx ended because the user cancelled the
+// generation, as opposed to the client going away.
+func cancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), domain.ErrGenerationCancelled)
+}
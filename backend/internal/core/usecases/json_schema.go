@@ -0,0 +1,181 @@
+package usecases
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+// parseStructuredOutput decodes an answer that was asked to follow format.
+// Models sometimes wrap JSON in a markdown code block, which is tolerated.
+func parseStructuredOutput(format *domain.ResponseFormat, content string) (json.RawMessage, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(content, "```")
+		content = strings.TrimSpace(content)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return nil, fmt.Errorf("answer is not valid JSON: %w", err)
+	}
+
+	if format.IsSchema() {
+		var schema map[string]interface{}
+		if err := json.Unmarshal(format.Schema, &schema); err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		if err := validateSchema(schema, value, "$"); err != nil {
+			return nil, err
+		}
+	}
+	return json.RawMessage(content), nil
+}
+
+// validateSchema checks value against the subset of JSON Schema that
+// structured output schemas use in practice: type, enum, const, properties,
+// required, additionalProperties, items and the numeric, length and size
+// bounds. Unknown keywords are ignored.
+func validateSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		return fmt.Errorf("%s: expected %v, got %s", path, types, jsonType(value))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if reflect.DeepEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of %v", path, enum)
+		}
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		return fmt.Errorf("%s: value must be %v", path, constant)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(schema, v, path)
+	case []interface{}:
+		if n, ok := schema["minItems"].(float64); ok && float64(len(v)) < n {
+			return fmt.Errorf("%s: expected at least %v items", path, n)
+		}
+		if n, ok := schema["maxItems"].(float64); ok && float64(len(v)) > n {
+			return fmt.Errorf("%s: expected at most %v items", path, n)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if n, ok := schema["minLength"].(float64); ok && length < n {
+			return fmt.Errorf("%s: expected at least %v characters", path, n)
+		}
+		if n, ok := schema["maxLength"].(float64); ok && length > n {
+			return fmt.Errorf("%s: expected at most %v characters", path, n)
+		}
+	case float64:
+		if n, ok := schema["minimum"].(float64); ok && v < n {
+			return fmt.Errorf("%s: expected a value of at least %v", path, n)
+		}
+		if n, ok := schema["maximum"].(float64); ok && v > n {
+			return fmt.Errorf("%s: expected a value of at most %v", path, n)
+		}
+	}
+	return nil
+}
+
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, exists := object[key]; !exists {
+					return fmt.Errorf("%s: missing required property %q", path, key)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for key, field := range object {
+		fieldPath := path + "." + key
+		if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+			if err := validateSchema(propertySchema, field, fieldPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: unexpected property", fieldPath)
+			}
+		case map[string]interface{}:
+			if err := validateSchema(additional, field, fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType accepts a single type name or a list of them.
+func matchesType(types interface{}, value interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		return matchesTypeName(t, value)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	if name == "integer" {
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	return name == jsonType(value) || (name == "number" && jsonType(value) == "integer")
+}
+
+// jsonType names the JSON type of a value decoded by encoding/json.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,106 @@
+package usecases
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+const personSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"age": {"type": "integer", "minimum": 0},
+		"height": {"type": "number"},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {
+			"type": "array",
+			"maxItems": 2,
+			"items": {
+				"type": "object",
+				"properties": {"label": {"type": "string"}},
+				"required": ["label"]
+			}
+		}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": false
+}`
+
+func TestParseStructuredOutputSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		valid   bool
+	}{
+		{"valid", `{"name": "Ada", "age": 36}`, true},
+		{"every property", `{"name": "Ada", "age": 36, "height": 1.65, "role": "admin", "tags": [{"label": "math"}]}`, true},
+		{"integral number", `{"name": "Ada", "age": 36, "height": 2}`, true},
+		{"exponent integer", `{"name": "Ada", "age": 3.6e1}`, true},
+		{"missing required", `{"name": "Ada"}`, false},
+		{"additional property", `{"name": "Ada", "age": 36, "email": "ada@example.com"}`, false},
+		{"fractional integer", `{"name": "Ada", "age": 36.5}`, false},
+		{"wrong type", `{"name": "Ada", "age": "36"}`, false},
+		{"below minimum", `{"name": "Ada", "age": -1}`, false},
+		{"too short", `{"name": "", "age": 36}`, false},
+		{"not in enum", `{"name": "Ada", "age": 36, "role": "guest"}`, false},
+		{"nested item missing required", `{"name": "Ada", "age": 36, "tags": [{"label": "math"}, {}]}`, false},
+		{"nested item wrong type", `{"name": "Ada", "age": 36, "tags": [{"label": 1}]}`, false},
+		{"too many items", `{"name": "Ada", "age": 36, "tags": [{"label": "a"}, {"label": "b"}, {"label": "c"}]}`, false},
+		{"not an object", `["Ada", 36]`, false},
+		{"not JSON", `Ada is 36`, false},
+	}
+
+	format := &domain.ResponseFormat{Schema: json.RawMessage(personSchema)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseStructuredOutput(format, tt.content)
+			if tt.valid && err != nil {
+				t.Errorf("rejected: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("accepted")
+			}
+		})
+	}
+}
+
+func TestParseStructuredOutputCodeFences(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"plain", `{"ok": true}`, `{"ok": true}`},
+		{"json fence", "```json\n{\"ok\": true}\n```", `{"ok": true}`},
+		{"bare fence", "```\n{\"ok\": true}\n```", `{"ok": true}`},
+		{"surrounding space", "  \n```json\n{\"ok\": true}\n```\n", `{"ok": true}`},
+		{"any JSON value", `[1, 2]`, `[1, 2]`},
+	}
+
+	format := &domain.ResponseFormat{Schema: json.RawMessage(`"json"`)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseStructuredOutput(format, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(parsed) != tt.want {
+				t.Errorf("got %s, want %s", parsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaTypeLists(t *testing.T) {
+	schema := map[string]interface{}{"type": []interface{}{"string", "null"}}
+	for _, value := range []interface{}{"text", nil} {
+		if err := validateSchema(schema, value, "$"); err != nil {
+			t.Errorf("%v rejected: %v", value, err)
+		}
+	}
+	if err := validateSchema(schema, float64(1), "$"); err == nil {
+		t.Error("number accepted by a string or null schema")
+	}
+}
@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// MessageIndexer stores an embedding for every saved message so chats can be
+// searched semantically.
+type MessageIndexer struct {
+	embeddings    ports.EmbeddingService
+	embeddingRepo ports.EmbeddingRepository
+	timeout       time.Duration
+}
+
+func NewMessageIndexer(embeddings ports.EmbeddingService, embeddingRepo ports.EmbeddingRepository) *MessageIndexer {
+	return &MessageIndexer{
+		embeddings:    embeddings,
+		embeddingRepo: embeddingRepo,
+		timeout:       time.Minute,
+	}
+}
+
+// Index embeds the message in the background so saving a message never
+// waits for the embedding model.
+func (i *MessageIndexer) Index(message *domain.Message) {
+	// Tool results are raw data rather than conversation worth searching
+	if message.Role == domain.SystemRole || message.Role == domain.ToolRole || message.Content == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), i.timeout)
+		defer cancel()
+
+		if err := i.IndexNow(ctx, message); err != nil {
+			log.Printf("Failed to index message %s: %v", uuid.UUID(message.ID), err)
+		}
+	}()
+}
+
+func (i *MessageIndexer) IndexNow(ctx context.Context, message *domain.Message) error {
+	vectors, err := i.embeddings.Embed(ctx, []string{message.Content})
+	if err != nil {
+		return fmt.Errorf("failed to embed message: %w", err)
+	}
+
+	if err := i.embeddingRepo.SaveMessageEmbedding(ctx, message, i.embeddings.Model(), vectors[0]); err != nil {
+		return fmt.Errorf("failed to save message embedding: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type modelUseCase struct {
+	modelManager ports.ModelManager
+}
+
+func NewModelUseCase(modelManager ports.ModelManager) ports.ModelUseCase {
+	return &modelUseCase{
+		modelManager: modelManager,
+	}
+}
+
+func (uc *modelUseCase) PullModel(ctx context.Context, name string) (<-chan domain.ModelPullProgress, error) {
+	progress, err := uc.modelManager.PullModel(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull model: %w", err)
+	}
+	return progress, nil
+}
+
+func (uc *modelUseCase) DeleteModel(ctx context.Context, name string) error {
+	if err := uc.modelManager.DeleteModel(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete model: %w", err)
+	}
+	return nil
+}
+
+func (uc *modelUseCase) CopyModel(ctx context.Context, source, destination string) error {
+	if source == destination {
+		return domain.ErrInvalidModelCopy
+	}
+
+	if err := uc.modelManager.CopyModel(ctx, source, destination); err != nil {
+		return fmt.Errorf("failed to copy model: %w", err)
+	}
+	return nil
+}
+
+func (uc *modelUseCase) PreloadModel(ctx context.Context, name string, keepAlive time.Duration) error {
+	if err := uc.modelManager.PreloadModel(ctx, name, keepAlive); err != nil {
+		return fmt.Errorf("failed to preload model: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type searchUseCase struct {
+	embeddings    ports.EmbeddingService
+	embeddingRepo ports.EmbeddingRepository
+}
+
+func NewSearchUseCase(embeddings ports.EmbeddingService, embeddingRepo ports.EmbeddingRepository) ports.SearchUseCase {
+	return &searchUseCase{
+		embeddings:    embeddings,
+		embeddingRepo: embeddingRepo,
+	}
+}
+
+func (uc *searchUseCase) SemanticSearch(ctx context.Context, query string, limit int) ([]domain.MessageSearchResult, error) {
+	vectors, err := uc.embeddings.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	results, err := uc.embeddingRepo.SearchMessages(ctx, uc.embeddings.Model(), vectors[0], limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+	return results, nil
+}
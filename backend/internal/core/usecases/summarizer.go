@@ -0,0 +1,171 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+const summarizerPrompt = `You maintain a running summary of a conversation between a user and an AI assistant.
+Combine the previous summary (if any) with the new messages into a single concise summary.
+Keep facts, decisions, names, code identifiers and open questions. Do not add commentary.
+Reply with the summary only.`
+
+type SummaryConfig struct {
+	// Model used to write summaries; empty uses the model of the chat turn
+	Model string
+	// Threshold is the number of unsummarized messages that triggers a summary
+	Threshold int
+	// KeepRecent messages are never summarized so the latest turns stay verbatim
+	KeepRecent int
+	// Timeout bounds a single background summarization
+	Timeout time.Duration
+}
+
+// Summarizer folds the oldest messages of long chats into a rolling summary
+// that is sent in place of those messages.
+type Summarizer struct {
+	chatRepo     ports.ChatRepository
+	summaryRepo  ports.SummaryRepository
+	modelService ports.AIModelService
+	config       SummaryConfig
+	// running holds the chats that are being summarized right now
+	running sync.Map
+}
+
+func NewSummarizer(chatRepo ports.ChatRepository, summaryRepo ports.SummaryRepository, modelService ports.AIModelService, config SummaryConfig) *Summarizer {
+	if config.Threshold <= 0 {
+		config.Threshold = 40
+	}
+	if config.KeepRecent <= 0 || config.KeepRecent >= config.Threshold {
+		config.KeepRecent = config.Threshold / 4
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Minute
+	}
+	return &Summarizer{
+		chatRepo:     chatRepo,
+		summaryRepo:  summaryRepo,
+		modelService: modelService,
+		config:       config,
+	}
+}
+
+// summaryPathLimit bounds how far up the branch Summarize looks for the
+// previous summary and the messages after it.
+const summaryPathLimit = 1000
+
+// Latest returns the latest summary of the branch made of messages, or nil
+// if it has none. Summaries of other branches never apply.
+func (s *Summarizer) Latest(ctx context.Context, chatID domain.ChatID, messages []*domain.Message) (*domain.ChatSummary, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	ids := make([]domain.MessageID, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+	return s.summaryRepo.GetLatestSummary(ctx, chatID, ids)
+}
+
+// unsummarized returns the messages of the branch that follow the message
+// the summary ends at.
+func unsummarized(summary *domain.ChatSummary, messages []*domain.Message) []*domain.Message {
+	if summary == nil || summary.CoveredMessageID == nil {
+		return messages
+	}
+	for i, m := range messages {
+		if m.ID == *summary.CoveredMessageID {
+			return messages[i+1:]
+		}
+	}
+	return messages
+}
+
+// Schedule summarizes the branch ending at leafID in the background if it
+// has grown past the threshold. Only one summarization per chat runs at a
+// time.
+func (s *Summarizer) Schedule(chatID domain.ChatID, leafID domain.MessageID, model string) {
+	if _, running := s.running.LoadOrStore(chatID, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer s.running.Delete(chatID)
+
+		// Summaries wait behind the chats people are waiting on
+		ctx, cancel := context.WithTimeout(domain.WithPriority(context.Background(), domain.PriorityLow), s.config.Timeout)
+		defer cancel()
+
+		if err := s.Summarize(ctx, chatID, leafID, model); err != nil {
+			log.Printf("Failed to summarize chat %s: %v", uuid.UUID(chatID), err)
+		}
+	}()
+}
+
+// Summarize folds the oldest span of unsummarized messages of the branch
+// ending at leafID into a new summary. It does nothing while the branch is
+// below the threshold.
+func (s *Summarizer) Summarize(ctx context.Context, chatID domain.ChatID, leafID domain.MessageID, model string) error {
+	path, err := s.chatRepo.GetAncestors(ctx, chatID, leafID, summaryPathLimit)
+	if err != nil {
+		return fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	previous, err := s.Latest(ctx, chatID, path)
+	if err != nil {
+		return fmt.Errorf("failed to get latest summary: %w", err)
+	}
+
+	messages := unsummarized(previous, path)
+	if len(messages) <= s.config.Threshold {
+		return nil
+	}
+
+	// More than Threshold messages remain, so leaving KeepRecent of the
+	// oldest Threshold out always leaves at least that many verbatim messages
+	span := messages[:s.config.Threshold-s.config.KeepRecent]
+
+	var transcript strings.Builder
+	if previous != nil {
+		fmt.Fprintf(&transcript, "Previous summary:\n%s\n\n", previous.Content)
+	}
+	transcript.WriteString("New messages:\n")
+	for _, m := range span {
+		fmt.Fprintf(&transcript, "%s: %s\n\n", m.Role, m.Content)
+	}
+
+	if s.config.Model != "" {
+		model = s.config.Model
+	}
+
+	temperature := 0.2
+	request := domain.NewMessage(chatID, transcript.String(), domain.UserRole, model)
+	request.Options = &domain.GenerationOptions{Temperature: &temperature}
+	system := domain.NewMessage(chatID, summarizerPrompt, domain.SystemRole, model)
+
+	response, err := s.modelService.SendMessage(ctx, request, []*domain.Message{system})
+	if err != nil {
+		return fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	count := len(span)
+	if previous != nil {
+		count += previous.MessageCount
+	}
+
+	summary := domain.NewChatSummary(chatID, strings.TrimSpace(response.Content), model, count, span[len(span)-1])
+	if err := s.summaryRepo.SaveSummary(ctx, summary); err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+
+	log.Printf("Summarized %d messages of chat %s", len(span), uuid.UUID(chatID))
+	return nil
+}
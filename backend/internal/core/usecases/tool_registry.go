@@ -0,0 +1,82 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+const (
+	defaultMaxToolRounds = 5
+	toolTimeout          = 30 * time.Second
+)
+
+// ToolRegistry holds the tools offered to models and runs the calls they
+// make.
+type ToolRegistry struct {
+	tools map[string]ports.Tool
+	// definitions keeps registration order so prompts are stable
+	definitions []domain.ToolDefinition
+	// maxRounds bounds how many times a single answer may call tools
+	maxRounds int
+}
+
+func NewToolRegistry(maxRounds int, tools ...ports.Tool) (*ToolRegistry, error) {
+	if maxRounds <= 0 {
+		maxRounds = defaultMaxToolRounds
+	}
+
+	r := &ToolRegistry{
+		tools:     make(map[string]ports.Tool, len(tools)),
+		maxRounds: maxRounds,
+	}
+	for _, tool := range tools {
+		if _, exists := r.tools[tool.Name()]; exists {
+			return nil, fmt.Errorf("tool %q is registered twice", tool.Name())
+		}
+		r.tools[tool.Name()] = tool
+		r.definitions = append(r.definitions, domain.ToolDefinition{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  tool.Parameters(),
+		})
+	}
+	return r, nil
+}
+
+// Definitions returns the tools in the form sent to the model.
+func (r *ToolRegistry) Definitions() []domain.ToolDefinition {
+	return r.definitions
+}
+
+// Execute runs a tool call and returns the tool message answering it.
+// Failures are reported to the model as the result so it can recover.
+func (r *ToolRegistry) Execute(ctx context.Context, chatID domain.ChatID, model string, call domain.ToolCall) *domain.Message {
+	result, err := r.execute(ctx, call)
+	if err != nil {
+		log.Printf("Tool %s failed in chat %s: %v", call.Name, uuid.UUID(chatID), err)
+		result = "Error: " + err.Error()
+	}
+
+	message := domain.NewMessage(chatID, result, domain.ToolRole, model)
+	message.ToolCallID = call.ID
+	message.ToolName = call.Name
+	return message
+}
+
+func (r *ToolRegistry) execute(ctx context.Context, call domain.ToolCall) (string, error) {
+	tool, ok := r.tools[call.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, toolTimeout)
+	defer cancel()
+
+	return tool.Execute(ctx, call.Arguments)
+}
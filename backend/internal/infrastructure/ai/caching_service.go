@@ -0,0 +1,255 @@
+package ai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type CacheConfig struct {
+	// Size is the number of responses kept, least recently used go first
+	Size int
+	TTL  time.Duration
+}
+
+func (c *CacheConfig) applyDefaults() {
+	if c.Size <= 0 {
+		c.Size = 1000
+	}
+	if c.TTL <= 0 {
+		c.TTL = time.Hour
+	}
+}
+
+// cachedResponse is what is needed to replay an answer.
+type cachedResponse struct {
+	content    string
+	model      string
+	provider   string
+	toolCalls  domain.ToolCalls
+	doneReason string
+}
+
+type cacheEntry struct {
+	key      string
+	response cachedResponse
+	expires  time.Time
+}
+
+// responseCache is an LRU of responses whose entries expire after a TTL.
+type responseCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newResponseCache(size int, ttl time.Duration) *responseCache {
+	return &responseCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *responseCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return cachedResponse{}, false
+	}
+	c.order.MoveToFront(element)
+	return entry.response, true
+}
+
+func (c *responseCache) put(key string, response cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, response: response, expires: time.Now().Add(c.ttl)})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// CachingService answers repeated deterministic requests from memory. Only
+// requests with temperature 0 or a fixed seed are cached, any other request
+// is expected to produce a different answer each time.
+type CachingService struct {
+	ports.AIModelService
+	cache *responseCache
+}
+
+func NewCachingService(service ports.AIModelService, config CacheConfig) ports.AIModelService {
+	config.applyDefaults()
+	return &CachingService{
+		AIModelService: service,
+		cache:          newResponseCache(config.Size, config.TTL),
+	}
+}
+
+type cacheKeyMessage struct {
+	Role       domain.MessageRole `json:"role"`
+	Content    string             `json:"content"`
+	ToolCalls  domain.ToolCalls   `json:"tool_calls,omitempty"`
+	ToolCallID string             `json:"tool_call_id,omitempty"`
+	ToolName   string             `json:"tool_name,omitempty"`
+	// Images are the hashes of the attached images
+	Images []string `json:"images,omitempty"`
+}
+
+// cacheKey hashes everything that shapes the answer: the model, its
+// options, the offered tools, the format and the whole conversation
+// including the system prompt.
+func cacheKey(message *domain.Message, history []*domain.Message) (string, error) {
+	messages := make([]cacheKeyMessage, 0, len(history)+1)
+	for _, m := range append(history[:len(history):len(history)], message) {
+		km := cacheKeyMessage{
+			Role:       m.Role,
+			Content:    m.Content,
+			ToolCalls:  m.ToolCalls,
+			ToolCallID: m.ToolCallID,
+			ToolName:   m.ToolName,
+		}
+		for _, a := range m.Attachments {
+			if a.Data != nil {
+				sum := sha256.Sum256(a.Data)
+				km.Images = append(km.Images, hex.EncodeToString(sum[:]))
+			}
+		}
+		messages = append(messages, km)
+	}
+
+	data, err := json.Marshal(struct {
+		Model    string                    `json:"model"`
+		Options  *domain.GenerationOptions `json:"options"`
+		Tools    []domain.ToolDefinition   `json:"tools"`
+		Format   *domain.ResponseFormat    `json:"format"`
+		Messages []cacheKeyMessage         `json:"messages"`
+	}{message.Model, message.Options, message.Tools, message.Format, messages})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lookup returns the cache key of a cacheable request, or "" when the
+// request must not be cached.
+func (s *CachingService) lookup(message *domain.Message, history []*domain.Message) (string, *cachedResponse) {
+	if !message.Options.Deterministic() {
+		return "", nil
+	}
+	key, err := cacheKey(message, history)
+	if err != nil {
+		return "", nil
+	}
+	if response, ok := s.cache.get(key); ok {
+		return key, &response
+	}
+	return key, nil
+}
+
+// replay turns a cached response into a new message. Tool calls are copied
+// since callers fill in missing call IDs.
+func (r *cachedResponse) replay(chatID domain.ChatID) *domain.Message {
+	message := domain.NewMessage(chatID, r.content, domain.AssistantRole, r.model)
+	message.Provider = r.provider
+	message.ToolCalls = append(domain.ToolCalls(nil), r.toolCalls...)
+	message.Cached = true
+	return message
+}
+
+func (s *CachingService) SendMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	key, hit := s.lookup(message, history)
+	if hit != nil {
+		return hit.replay(message.ChatID), nil
+	}
+
+	response, err := s.AIModelService.SendMessage(ctx, message, history)
+	if err != nil || key == "" {
+		return response, err
+	}
+
+	s.cache.put(key, cachedResponse{
+		content:   response.Content,
+		model:     response.Model,
+		provider:  response.Provider,
+		toolCalls: append(domain.ToolCalls(nil), response.ToolCalls...),
+	})
+	return response, nil
+}
+
+// StreamMessage replays a cached answer as a single chunk. Streams are only
+// cached once they completed.
+func (s *CachingService) StreamMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	key, hit := s.lookup(message, history)
+	if hit != nil {
+		chunks := make(chan domain.MessageChunk, 2)
+		chunks <- domain.MessageChunk{Content: hit.content, Provider: hit.provider}
+		chunks <- domain.MessageChunk{
+			Done:       true,
+			DoneReason: hit.doneReason,
+			Provider:   hit.provider,
+			ToolCalls:  append(domain.ToolCalls(nil), hit.toolCalls...),
+			Cached:     true,
+		}
+		close(chunks)
+		return chunks, nil
+	}
+
+	stream, err := s.AIModelService.StreamMessage(ctx, message, history)
+	if err != nil || key == "" {
+		return stream, err
+	}
+
+	chunks := make(chan domain.MessageChunk)
+	go func() {
+		defer close(chunks)
+		var content []byte
+		for chunk := range stream {
+			content = append(content, chunk.Content...)
+			if chunk.Done && chunk.Err == nil {
+				s.cache.put(key, cachedResponse{
+					content:    string(content),
+					model:      message.Model,
+					provider:   chunk.Provider,
+					toolCalls:  append(domain.ToolCalls(nil), chunk.ToolCalls...),
+					doneReason: chunk.DoneReason,
+				})
+			}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
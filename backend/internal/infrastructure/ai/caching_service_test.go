@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// countingService numbers its answers so a replay can be told apart from a
+// new generation.
+type countingService struct {
+	ports.AIModelService
+	calls int
+}
+
+func (c *countingService) SendMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	c.calls++
+	return domain.NewMessage(message.ChatID, fmt.Sprintf("answer %d", c.calls), domain.AssistantRole, message.Model), nil
+}
+
+func (c *countingService) StreamMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	c.calls++
+	chunks := make(chan domain.MessageChunk, 2)
+	chunks <- domain.MessageChunk{Content: fmt.Sprintf("answer %d", c.calls)}
+	chunks <- domain.MessageChunk{Done: true, DoneReason: "stop"}
+	close(chunks)
+	return chunks, nil
+}
+
+func float(v float64) *float64 { return &v }
+func integer(v int) *int       { return &v }
+
+func cacheRequest(content string, options *domain.GenerationOptions) *domain.Message {
+	message := domain.NewMessage(domain.ChatID(uuid.New()), content, domain.UserRole, "llama3")
+	message.Options = options
+	return message
+}
+
+func TestCachingServiceOnlyCachesDeterministicRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *domain.GenerationOptions
+		cached  bool
+	}{
+		{"no options", nil, false},
+		{"sampling", &domain.GenerationOptions{Temperature: float(0.7)}, false},
+		{"greedy", &domain.GenerationOptions{Temperature: float(0)}, true},
+		{"fixed seed", &domain.GenerationOptions{Temperature: float(0.7), Seed: integer(42)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &countingService{}
+			s := NewCachingService(fake, CacheConfig{})
+			for i := 0; i < 2; i++ {
+				if _, err := s.SendMessage(context.Background(), cacheRequest("hi", tt.options), nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want := 2
+			if tt.cached {
+				want = 1
+			}
+			if fake.calls != want {
+				t.Errorf("backend got %d calls, want %d", fake.calls, want)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	greedy := &domain.GenerationOptions{Temperature: float(0)}
+	system := domain.NewMessage(domain.ChatID(uuid.New()), "be brief", domain.SystemRole, "llama3")
+	base, err := cacheKey(cacheRequest("hi", greedy), []*domain.Message{system})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another chat asking the same is a hit
+	same, _ := cacheKey(cacheRequest("hi", greedy), []*domain.Message{system})
+	if same != base {
+		t.Error("the same request got another key")
+	}
+
+	tests := []struct {
+		name    string
+		message func() *domain.Message
+		history []*domain.Message
+	}{
+		{"content", func() *domain.Message { return cacheRequest("hello", greedy) }, []*domain.Message{system}},
+		{"model", func() *domain.Message {
+			m := cacheRequest("hi", greedy)
+			m.Model = "mistral"
+			return m
+		}, []*domain.Message{system}},
+		{"options", func() *domain.Message {
+			return cacheRequest("hi", &domain.GenerationOptions{Temperature: float(0), NumPredict: integer(10)})
+		}, []*domain.Message{system}},
+		{"format", func() *domain.Message {
+			m := cacheRequest("hi", greedy)
+			m.Format = &domain.ResponseFormat{Schema: json.RawMessage(`"json"`)}
+			return m
+		}, []*domain.Message{system}},
+		{"image", func() *domain.Message {
+			m := cacheRequest("hi", greedy)
+			m.Attachments = domain.Attachments{{Data: []byte("png")}}
+			return m
+		}, []*domain.Message{system}},
+		{"system prompt", func() *domain.Message { return cacheRequest("hi", greedy) }, []*domain.Message{
+			domain.NewMessage(domain.ChatID(uuid.New()), "be verbose", domain.SystemRole, "llama3"),
+		}},
+		{"history", func() *domain.Message { return cacheRequest("hi", greedy) }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := cacheKey(tt.message(), tt.history)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == base {
+				t.Errorf("a different %s got the same key", tt.name)
+			}
+		})
+	}
+}
+
+func TestResponseCacheExpiresEntries(t *testing.T) {
+	cache := newResponseCache(10, time.Hour)
+	cache.put("a", cachedResponse{content: "a"})
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("fresh entry missed")
+	}
+
+	cache.mu.Lock()
+	cache.entries["a"].Value.(*cacheEntry).expires = time.Now().Add(-time.Second)
+	cache.mu.Unlock()
+	if _, ok := cache.get("a"); ok {
+		t.Error("expired entry hit")
+	}
+	if len(cache.entries) != 0 || cache.order.Len() != 0 {
+		t.Error("expired entry was kept")
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResponseCache(2, time.Hour)
+	cache.put("a", cachedResponse{content: "a"})
+	cache.put("b", cachedResponse{content: "b"})
+	// Reading a makes b the least recently used
+	cache.get("a")
+	cache.put("c", cachedResponse{content: "c"})
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.get(key); ok != want {
+			t.Errorf("get(%s) hit = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestCachingServiceReplaysStreams(t *testing.T) {
+	fake := &countingService{}
+	s := NewCachingService(fake, CacheConfig{})
+	greedy := &domain.GenerationOptions{Temperature: float(0)}
+
+	stream := func() (string, domain.MessageChunk) {
+		chunks, err := s.StreamMessage(context.Background(), cacheRequest("hi", greedy), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var content string
+		var last domain.MessageChunk
+		for chunk := range chunks {
+			content += chunk.Content
+			last = chunk
+		}
+		return content, last
+	}
+
+	first, done := stream()
+	if done.Cached {
+		t.Error("first stream reported as cached")
+	}
+	replayed, done := stream()
+	if replayed != first || !done.Cached || done.DoneReason != "stop" {
+		t.Errorf("replay got %q %+v, want %q", replayed, done, first)
+	}
+	if fake.calls != 1 {
+		t.Errorf("backend got %d calls, want 1", fake.calls)
+	}
+}
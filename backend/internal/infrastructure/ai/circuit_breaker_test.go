@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+// cool lets the breaker's cooldown pass without waiting for it.
+func cool(b *circuitBreaker) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.openedAt = time.Now().Add(-b.cooldown)
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps func(b *circuitBreaker)
+		want  circuitState
+		allow bool
+	}{
+		{
+			name: "failures below the threshold keep it closed",
+			steps: func(b *circuitBreaker) {
+				b.failure()
+				b.failure()
+			},
+			want:  circuitClosed,
+			allow: true,
+		},
+		{
+			name: "a success resets the failure count",
+			steps: func(b *circuitBreaker) {
+				b.failure()
+				b.failure()
+				b.success()
+				b.failure()
+				b.failure()
+			},
+			want:  circuitClosed,
+			allow: true,
+		},
+		{
+			name: "the threshold opens it",
+			steps: func(b *circuitBreaker) {
+				b.failure()
+				b.failure()
+				b.failure()
+			},
+			want:  circuitOpen,
+			allow: false,
+		},
+		{
+			name: "the cooldown lets a single probe through",
+			steps: func(b *circuitBreaker) {
+				for i := 0; i < 3; i++ {
+					b.failure()
+				}
+				cool(b)
+				if !b.allow() {
+					t.Error("probe was not allowed after the cooldown")
+				}
+			},
+			want:  circuitHalfOpen,
+			allow: false,
+		},
+		{
+			name: "a successful probe closes it",
+			steps: func(b *circuitBreaker) {
+				for i := 0; i < 3; i++ {
+					b.failure()
+				}
+				cool(b)
+				b.allow()
+				b.success()
+			},
+			want:  circuitClosed,
+			allow: true,
+		},
+		{
+			name: "a failed probe reopens it",
+			steps: func(b *circuitBreaker) {
+				for i := 0; i < 3; i++ {
+					b.failure()
+				}
+				cool(b)
+				b.allow()
+				b.failure()
+			},
+			want:  circuitOpen,
+			allow: false,
+		},
+		{
+			name: "a released probe lets the next one through",
+			steps: func(b *circuitBreaker) {
+				for i := 0; i < 3; i++ {
+					b.failure()
+				}
+				cool(b)
+				b.allow()
+				b.release()
+			},
+			want:  circuitHalfOpen,
+			allow: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(3, time.Minute)
+			tt.steps(b)
+			if b.state != tt.want {
+				t.Errorf("state = %d, want %d", b.state, tt.want)
+			}
+			if got := b.allow(); got != tt.allow {
+				t.Errorf("allow() = %v, want %v", got, tt.allow)
+			}
+		})
+	}
+}
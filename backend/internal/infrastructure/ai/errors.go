@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+var (
+	ErrUnauthorized        = errors.New("provider rejected credentials")
+	ErrModelNotFound       = domain.ErrModelNotFound
+	ErrRateLimited         = errors.New("provider rate limit exceeded")
+	ErrProviderUnavailable = domain.ErrProviderUnavailable
+	ErrProviderTimeout     = domain.ErrProviderTimeout
+	ErrModelFailed         = domain.ErrModelFailed
+	ErrQueueFull           = domain.ErrQueueFull
+)
+
+// APIError is a non-2xx response from a model provider. It unwraps to one of
+// the sentinel errors above so callers can use errors.Is without caring which
+// provider produced it.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("%s error (status %d, %s): %s", e.Provider, e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("%s error (status %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusNotFound:
+		return ErrModelNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusBadGateway || e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusGatewayTimeout:
+		return ErrProviderUnavailable
+	case e.StatusCode >= http.StatusInternalServerError:
+		// Other server errors come from the model at hand, such as running
+		// out of memory, and say nothing about the backend's other models
+		return ErrModelFailed
+	default:
+		return nil
+	}
+}
+
+// classify wraps transport failures in the sentinel errors so callers can
+// tell a dead or slow backend apart from a bad request.
+func classify(err error) error {
+	if err == nil || errors.Is(err, ErrProviderUnavailable) || errors.Is(err, ErrProviderTimeout) {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrProviderTimeout, err)
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return fmt.Errorf("%w: %w", ErrProviderUnavailable, err)
+	}
+	return err
+}
+
+// isTransient reports whether a failed call is worth retrying: the backend
+// could not be reached, is overloaded, or is still loading the model.
+func isTransient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		case http.StatusInternalServerError:
+			return strings.Contains(strings.ToLower(apiErr.Message), "loading")
+		}
+		return false
+	}
+
+	// Only connection failures are retried, a response timeout already
+	// waited long enough
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
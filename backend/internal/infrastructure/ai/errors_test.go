@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyAPIErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		message   string
+		want      error
+		transient bool
+	}{
+		{401, "bad key", ErrUnauthorized, false},
+		{403, "forbidden", ErrUnauthorized, false},
+		{404, "model not found", ErrModelNotFound, false},
+		{429, "slow down", ErrRateLimited, true},
+		{500, "model is still loading", ErrModelFailed, true},
+		{500, "out of memory", ErrModelFailed, false},
+		{502, "bad gateway", ErrProviderUnavailable, true},
+		{503, "overloaded", ErrProviderUnavailable, true},
+		{504, "gateway timeout", ErrProviderUnavailable, true},
+		{507, "insufficient storage", ErrModelFailed, false},
+		{400, "bad request", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d %s", tt.status, tt.message), func(t *testing.T) {
+			err := classify(fmt.Errorf("failed to send: %w", &APIError{Provider: "test", StatusCode: tt.status, Message: tt.message}))
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("classify() = %v, want %v", err, tt.want)
+			}
+			for _, other := range []error{ErrUnauthorized, ErrModelNotFound, ErrRateLimited, ErrModelFailed, ErrProviderUnavailable, ErrProviderTimeout} {
+				if other != tt.want && errors.Is(err, other) {
+					t.Errorf("classify() = %v, also matches %v", err, other)
+				}
+			}
+			if got := isTransient(err); got != tt.transient {
+				t.Errorf("isTransient() = %v, want %v", got, tt.transient)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyTransportErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      error
+		transient bool
+	}{
+		{"nil", nil, nil, false},
+		{"deadline", fmt.Errorf("failed to send request: %w", context.DeadlineExceeded), ErrProviderTimeout, false},
+		{"read timeout", &net.OpError{Op: "read", Err: timeoutError{}}, ErrProviderTimeout, false},
+		{"dial refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, ErrProviderUnavailable, true},
+		{"reset", fmt.Errorf("failed to read: %w", syscall.ECONNRESET), ErrProviderUnavailable, true},
+		{"cancelled", context.Canceled, nil, false},
+		{"other", errors.New("failed to decode response"), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classify(tt.err)
+			if tt.want == nil {
+				if errors.Is(err, ErrProviderTimeout) || errors.Is(err, ErrProviderUnavailable) {
+					t.Errorf("classify() = %v, want it left alone", err)
+				}
+			} else if !errors.Is(err, tt.want) {
+				t.Errorf("classify() = %v, want %v", err, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("classify() = %v, lost the original error", err)
+			}
+			if got := isTransient(err); got != tt.transient {
+				t.Errorf("isTransient() = %v, want %v", got, tt.transient)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package ai
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// NewHTTPClient returns a client for talking to model providers. The
+// response timeout bounds the wait for response headers rather than the
+// whole request, so long streams are not cut off; for non-streamed
+// generations it bounds the generation itself. Zero disables a timeout.
+func NewHTTPClient(connectTimeout, responseTimeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = responseTimeout
+	return &http.Client{Transport: transport}
+}
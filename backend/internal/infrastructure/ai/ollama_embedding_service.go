@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type OllamaEmbeddingService struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewOllamaEmbeddingService uses client for every request, a nil client
+// means no timeouts.
+func NewOllamaEmbeddingService(baseURL, model string, client *http.Client) ports.EmbeddingService {
+	if baseURL == "" {
+		baseURL = os.Getenv("OLLAMA_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:11434"
+		}
+	}
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &OllamaEmbeddingService{
+		baseURL: baseURL,
+		model:   model,
+		client:  client,
+	}
+}
+
+func (s *OllamaEmbeddingService) Model() string {
+	return s.model
+}
+
+func (s *OllamaEmbeddingService) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"model": s.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/api/embed", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	// Errors from a proxy in front of Ollama may not be JSON
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		message := result.Error
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return nil, &APIError{Provider: "ollama", StatusCode: resp.StatusCode, Message: message}
+	}
+
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Embeddings))
+	}
+
+	return result.Embeddings, nil
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+// doJSON sends body to an Ollama endpoint and returns the response if it has
+// a 2xx status.
+func (s *OllamaService) doJSON(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, s.decodeError(resp)
+	}
+	return resp, nil
+}
+
+func (s *OllamaService) PullModel(ctx context.Context, name string) (<-chan domain.ModelPullProgress, error) {
+	resp, err := s.doJSON(ctx, "POST", "/api/pull", map[string]interface{}{
+		"model":  name,
+		"stream": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make(chan domain.ModelPullProgress)
+	go func() {
+		defer close(progress)
+		defer resp.Body.Close()
+
+		send := func(p domain.ModelPullProgress) bool {
+			select {
+			case progress <- p:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var update struct {
+				domain.ModelPullProgress
+				Error string `json:"error"`
+			}
+			if err := decoder.Decode(&update); err != nil {
+				if err == io.EOF {
+					// The stream always ends with "success" when the pull finished
+					send(domain.ModelPullProgress{Err: fmt.Errorf("pull of %s ended without success", name)})
+					return
+				}
+				send(domain.ModelPullProgress{Err: fmt.Errorf("failed to decode pull progress: %w", err)})
+				return
+			}
+
+			if update.Error != "" {
+				send(domain.ModelPullProgress{Err: fmt.Errorf("ollama error: %s", update.Error)})
+				return
+			}
+
+			if !send(update.ModelPullProgress) || update.Status == "success" {
+				return
+			}
+		}
+	}()
+
+	return progress, nil
+}
+
+func (s *OllamaService) DeleteModel(ctx context.Context, name string) error {
+	resp, err := s.doJSON(ctx, "DELETE", "/api/delete", map[string]string{"model": name})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *OllamaService) CopyModel(ctx context.Context, source, destination string) error {
+	resp, err := s.doJSON(ctx, "POST", "/api/copy", map[string]string{
+		"source":      source,
+		"destination": destination,
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PreloadModel loads the model into memory by sending a generate request
+// without a prompt. A zero keepAlive uses the server default and a negative
+// one keeps the model loaded indefinitely.
+func (s *OllamaService) PreloadModel(ctx context.Context, name string, keepAlive time.Duration) error {
+	body := map[string]interface{}{
+		"model":  name,
+		"stream": false,
+	}
+	if keepAlive < 0 {
+		body["keep_alive"] = -1
+	} else if keepAlive > 0 {
+		body["keep_alive"] = keepAlive.String()
+	}
+
+	resp, err := s.doJSON(ctx, "POST", "/api/generate", body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
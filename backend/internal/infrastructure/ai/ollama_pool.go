@@ -0,0 +1,545 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type PoolConfig struct {
+	// HealthInterval is how often every host is checked
+	HealthInterval time.Duration
+	// HealthTimeout bounds a single health check
+	HealthTimeout time.Duration
+}
+
+func (c *PoolConfig) applyDefaults() {
+	if c.HealthInterval <= 0 {
+		c.HealthInterval = 10 * time.Second
+	}
+	if c.HealthTimeout <= 0 {
+		c.HealthTimeout = 5 * time.Second
+	}
+}
+
+// ollamaHost is one Ollama server of the pool. All fields but url and
+// service are guarded by the pool's mutex.
+type ollamaHost struct {
+	url     string
+	service *OllamaService
+
+	healthy  bool
+	inflight int
+	// models are the pulled models, loaded the ones currently in memory
+	models map[string]bool
+	loaded map[string]bool
+}
+
+// ollamaModelName adds the tag Ollama implies, so "llama3" and
+// "llama3:latest" name the same model.
+func ollamaModelName(name string) string {
+	base := name[strings.LastIndex(name, "/")+1:]
+	if name != "" && !strings.Contains(base, ":") {
+		return name + ":latest"
+	}
+	return name
+}
+
+// hostDown reports whether err shows the host itself cannot serve requests,
+// as opposed to failing this one request.
+func hostDown(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	err = classify(err)
+	return errors.Is(err, ErrProviderUnavailable) || errors.Is(err, ErrProviderTimeout)
+}
+
+// OllamaPool spreads requests over several Ollama servers. A request goes to
+// a host that already has the model loaded, then to one that has it pulled,
+// and among those to the one with the fewest requests in flight. Hosts that
+// cannot be reached are ejected and re-admitted once a health check against
+// /api/tags succeeds again; the checks also refresh what every host has
+// pulled and, through /api/ps, loaded.
+type OllamaPool struct {
+	hosts  []*ollamaHost
+	config PoolConfig
+
+	mu sync.Mutex
+	// next rotates where the search for a host starts, spreading ties
+	next int
+}
+
+// NewOllamaPool checks the hosts in the background until ctx is done. Hosts
+// are assumed healthy until their first check says otherwise.
+func NewOllamaPool(ctx context.Context, baseURLs []string, client *http.Client, config PoolConfig) ports.AIModelService {
+	config.applyDefaults()
+	p := &OllamaPool{config: config}
+	for _, baseURL := range baseURLs {
+		service := NewOllamaService(baseURL, client).(*OllamaService)
+		p.hosts = append(p.hosts, &ollamaHost{
+			url:     service.baseURL,
+			service: service,
+			healthy: true,
+			models:  make(map[string]bool),
+			loaded:  make(map[string]bool),
+		})
+	}
+
+	go p.watch(ctx)
+	return p
+}
+
+func (p *OllamaPool) watch(ctx context.Context) {
+	ticker := time.NewTicker(p.config.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, h := range p.hosts {
+			wg.Add(1)
+			go func(h *ollamaHost) {
+				defer wg.Done()
+				p.check(ctx, h)
+			}(h)
+		}
+		wg.Wait()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *OllamaPool) check(ctx context.Context, h *ollamaHost) {
+	checkCtx, cancel := context.WithTimeout(ctx, p.config.HealthTimeout)
+	defer cancel()
+
+	tags, err := h.service.listTags(checkCtx)
+	var running []string
+	if err == nil {
+		running, err = h.service.listRunning(checkCtx)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		if ctx.Err() == nil {
+			p.eject(h, err)
+		}
+		return
+	}
+
+	h.models = make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		h.models[ollamaModelName(tag.Name)] = true
+	}
+	h.loaded = make(map[string]bool, len(running))
+	for _, name := range running {
+		h.loaded[ollamaModelName(name)] = true
+	}
+
+	if !h.healthy {
+		h.healthy = true
+		log.Printf("Re-admitting ollama host %s", h.url)
+	}
+}
+
+// eject takes h out of rotation until its next successful health check. The
+// caller must hold p.mu.
+func (p *OllamaPool) eject(h *ollamaHost, err error) {
+	if h.healthy {
+		h.healthy = false
+		log.Printf("Ejecting ollama host %s: %v", h.url, err)
+	}
+}
+
+// acquire picks the best healthy host for model that is not in tried and
+// counts a request in flight on it.
+func (p *OllamaPool) acquire(model string, tried map[*ollamaHost]bool) (*ollamaHost, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	model = ollamaModelName(model)
+	rank := func(h *ollamaHost) int {
+		switch {
+		case h.loaded[model]:
+			return 0
+		case h.models[model]:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	var best *ollamaHost
+	for i := range p.hosts {
+		h := p.hosts[(p.next+i)%len(p.hosts)]
+		if !h.healthy || tried[h] {
+			continue
+		}
+		if best == nil || rank(h) < rank(best) || (rank(h) == rank(best) && h.inflight < best.inflight) {
+			best = h
+		}
+	}
+	p.next++
+
+	if best == nil {
+		return nil, fmt.Errorf("%w: no healthy ollama host", ErrProviderUnavailable)
+	}
+	best.inflight++
+	return best, nil
+}
+
+// release ends a request on h. A success means the host now has the model
+// loaded, a failure of the host itself ejects it.
+func (p *OllamaPool) release(ctx context.Context, h *ollamaHost, model string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h.inflight--
+	switch {
+	case err == nil && model != "":
+		model = ollamaModelName(model)
+		h.models[model] = true
+		h.loaded[model] = true
+	case err != nil && ctx.Err() == nil && hostDown(err):
+		p.eject(h, err)
+	}
+}
+
+// run calls op on the best host for model, moving on to the next host while
+// hosts turn out to be down. On success the host is returned still acquired.
+func (p *OllamaPool) run(ctx context.Context, model string, op func(h *ollamaHost) error) (*ollamaHost, error) {
+	tried := make(map[*ollamaHost]bool)
+	var lastErr error
+	for {
+		h, err := p.acquire(model, tried)
+		if err != nil {
+			// Report why the last host failed rather than that none is left
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		err = op(h)
+		if err == nil {
+			return h, nil
+		}
+		p.release(ctx, h, model, err)
+		if ctx.Err() != nil || !hostDown(err) {
+			return nil, err
+		}
+		tried[h] = true
+		lastErr = err
+	}
+}
+
+func (p *OllamaPool) healthyHosts() []*ollamaHost {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var hosts []*ollamaHost
+	for _, h := range p.hosts {
+		if h.healthy {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+
+func (p *OllamaPool) SendMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	var response *domain.Message
+	h, err := p.run(ctx, msg.Model, func(h *ollamaHost) error {
+		var err error
+		response, err = h.service.SendMessage(ctx, msg, history)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.release(ctx, h, msg.Model, nil)
+	return response, nil
+}
+
+// StreamMessage counts the stream as in flight on its host until it ends.
+// Only starting the stream fails over to another host.
+func (p *OllamaPool) StreamMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	var stream <-chan domain.MessageChunk
+	h, err := p.run(ctx, msg.Model, func(h *ollamaHost) error {
+		var err error
+		stream, err = h.service.StreamMessage(ctx, msg, history)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan domain.MessageChunk)
+	go func() {
+		defer close(chunks)
+		var streamErr error
+		defer func() { p.release(ctx, h, msg.Model, streamErr) }()
+
+		for chunk := range stream {
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+			}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+// ListModels merges the models of all healthy hosts. A model pulled on
+// several hosts is reported once.
+func (p *OllamaPool) ListModels(ctx context.Context, detailed bool) ([]domain.ModelInfo, error) {
+	hosts := p.healthyHosts()
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%w: no healthy ollama host", ErrProviderUnavailable)
+	}
+
+	results := make([][]domain.ModelInfo, len(hosts))
+	errs := make([]error, len(hosts))
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h *ollamaHost) {
+			defer wg.Done()
+			results[i], errs[i] = h.service.ListModels(ctx, detailed)
+		}(i, h)
+	}
+	wg.Wait()
+
+	var models []domain.ModelInfo
+	seen := make(map[string]bool)
+	var firstErr error
+	for i, h := range hosts {
+		if errs[i] != nil {
+			log.Printf("Failed to list models of ollama host %s: %v", h.url, errs[i])
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			if ctx.Err() == nil && hostDown(errs[i]) {
+				p.mu.Lock()
+				p.eject(h, errs[i])
+				p.mu.Unlock()
+			}
+			continue
+		}
+		for _, model := range results[i] {
+			if !seen[model.Name] {
+				seen[model.Name] = true
+				models = append(models, model)
+			}
+		}
+	}
+
+	if models == nil && firstErr != nil {
+		return nil, firstErr
+	}
+	return models, nil
+}
+
+func (p *OllamaPool) GetModelInfo(ctx context.Context, name string) (*domain.ModelInfo, error) {
+	var info *domain.ModelInfo
+	h, err := p.run(ctx, name, func(h *ollamaHost) error {
+		var err error
+		info, err = h.service.GetModelInfo(ctx, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Showing a model does not load it
+	p.release(ctx, h, "", nil)
+	return info, nil
+}
+
+// each calls op on every healthy host. Hosts that do not have the model are
+// skipped, the model is only missing when no host has it.
+func (p *OllamaPool) each(op func(h *ollamaHost) error) error {
+	hosts := p.healthyHosts()
+	if len(hosts) == 0 {
+		return fmt.Errorf("%w: no healthy ollama host", ErrProviderUnavailable)
+	}
+
+	var notFound error
+	found := false
+	for _, h := range hosts {
+		err := op(h)
+		if errors.Is(err, ErrModelNotFound) {
+			notFound = err
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed on ollama host %s: %w", h.url, err)
+		}
+		found = true
+	}
+	if !found {
+		return notFound
+	}
+	return nil
+}
+
+// PullModel pulls the model onto every healthy host, one after another, so
+// that any of them can serve it. Progress of all hosts is reported on one
+// channel and only the last host reports success.
+func (p *OllamaPool) PullModel(ctx context.Context, name string) (<-chan domain.ModelPullProgress, error) {
+	hosts := p.healthyHosts()
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%w: no healthy ollama host", ErrProviderUnavailable)
+	}
+
+	// Starting the first pull right away keeps start failures synchronous
+	first, err := hosts[0].service.PullModel(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make(chan domain.ModelPullProgress)
+	go func() {
+		defer close(progress)
+
+		send := func(update domain.ModelPullProgress) bool {
+			select {
+			case progress <- update:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for i, h := range hosts {
+			updates := first
+			if i > 0 {
+				var err error
+				updates, err = h.service.PullModel(ctx, name)
+				if err != nil {
+					send(domain.ModelPullProgress{Err: fmt.Errorf("failed on ollama host %s: %w", h.url, err)})
+					return
+				}
+			}
+
+			pulled := false
+			for update := range updates {
+				if update.Status == "success" {
+					pulled = true
+					p.mu.Lock()
+					h.models[ollamaModelName(name)] = true
+					p.mu.Unlock()
+					if i < len(hosts)-1 {
+						continue
+					}
+				}
+				if !send(update) || update.Err != nil {
+					return
+				}
+			}
+			if !pulled {
+				// A host that stops reporting progress did not finish the pull
+				send(domain.ModelPullProgress{Err: fmt.Errorf("pull on ollama host %s ended without success", h.url)})
+				return
+			}
+		}
+	}()
+
+	return progress, nil
+}
+
+func (p *OllamaPool) DeleteModel(ctx context.Context, name string) error {
+	return p.each(func(h *ollamaHost) error {
+		if err := h.service.DeleteModel(ctx, name); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		delete(h.models, ollamaModelName(name))
+		delete(h.loaded, ollamaModelName(name))
+		p.mu.Unlock()
+		return nil
+	})
+}
+
+func (p *OllamaPool) CopyModel(ctx context.Context, source, destination string) error {
+	return p.each(func(h *ollamaHost) error {
+		if err := h.service.CopyModel(ctx, source, destination); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		h.models[ollamaModelName(destination)] = true
+		p.mu.Unlock()
+		return nil
+	})
+}
+
+// PreloadModel loads the model on the host it would be placed on.
+func (p *OllamaPool) PreloadModel(ctx context.Context, name string, keepAlive time.Duration) error {
+	h, err := p.run(ctx, name, func(h *ollamaHost) error {
+		return h.service.PreloadModel(ctx, name, keepAlive)
+	})
+	if err != nil {
+		return err
+	}
+	p.release(ctx, h, name, nil)
+	return nil
+}
+
+// poolEmbeddingService computes embeddings on the pool's hosts, placed and
+// failed over like generations.
+type poolEmbeddingService struct {
+	pool      *OllamaPool
+	model     string
+	embedders map[*ollamaHost]ports.EmbeddingService
+}
+
+// EmbeddingService returns an embedding service for model that runs on the
+// pool's hosts.
+func (p *OllamaPool) EmbeddingService(model string) ports.EmbeddingService {
+	s := &poolEmbeddingService{pool: p, embedders: make(map[*ollamaHost]ports.EmbeddingService, len(p.hosts))}
+	for _, h := range p.hosts {
+		s.embedders[h] = NewOllamaEmbeddingService(h.url, model, h.service.client)
+		s.model = s.embedders[h].Model()
+	}
+	return s
+}
+
+func (s *poolEmbeddingService) Model() string {
+	return s.model
+}
+
+func (s *poolEmbeddingService) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var embeddings [][]float32
+	h, err := s.pool.run(ctx, s.model, func(h *ollamaHost) error {
+		var err error
+		embeddings, err = s.embedders[h].Embed(ctx, texts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.pool.release(ctx, h, s.model, nil)
+	return embeddings, nil
+}
@@ -0,0 +1,345 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+// fakeOllama is an Ollama server that answers every chat with its own URL.
+type fakeOllama struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	down   bool
+	models []string
+	loaded []string
+	delay  time.Duration
+	// pullStatuses are streamed in answer to a pull
+	pullStatuses []string
+}
+
+func newFakeOllama(t *testing.T, models, loaded []string) *fakeOllama {
+	f := &fakeOllama{models: models, loaded: loaded, pullStatuses: []string{"pulling manifest", "success"}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeOllama) set(update func(f *fakeOllama)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	update(f)
+}
+
+func (f *fakeOllama) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	down, delay := f.down, f.delay
+	models, loaded, statuses := f.models, f.loaded, f.pullStatuses
+	f.mu.Unlock()
+
+	if down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	names := func(names []string) map[string]interface{} {
+		list := make([]map[string]string, len(names))
+		for i, name := range names {
+			list[i] = map[string]string{"name": name}
+		}
+		return map[string]interface{}{"models": list}
+	}
+
+	switch r.URL.Path {
+	case "/api/tags":
+		json.NewEncoder(w).Encode(names(models))
+	case "/api/ps":
+		json.NewEncoder(w).Encode(names(loaded))
+	case "/api/chat":
+		time.Sleep(delay)
+		fmt.Fprintf(w, `{"message":{"role":"assistant","content":%q},"done":true}`, f.server.URL)
+	case "/api/embed":
+		fmt.Fprint(w, `{"embeddings":[[1,0]]}`)
+	case "/api/pull":
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, status := range statuses {
+			if status == "success" {
+				f.set(func(f *fakeOllama) { f.models = append(f.models, req.Model) })
+			}
+			fmt.Fprintf(w, "{\"status\":%q}\n", status)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestPool(t *testing.T, hosts ...*fakeOllama) *OllamaPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	urls := make([]string, len(hosts))
+	for i, h := range hosts {
+		urls[i] = h.server.URL
+	}
+	p := NewOllamaPool(ctx, urls, nil, PoolConfig{HealthInterval: 20 * time.Millisecond}).(*OllamaPool)
+	// Wait for the first health check to learn the models
+	waitFor(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i, h := range p.hosts {
+			if len(h.models) != len(hosts[i].models) {
+				return false
+			}
+		}
+		return true
+	})
+	return p
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func healthy(p *OllamaPool, i int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hosts[i].healthy
+}
+
+func ask(ctx context.Context, p *OllamaPool, model string) (string, error) {
+	message := domain.NewMessage(domain.ChatID(uuid.New()), "hi", domain.UserRole, model)
+	response, err := p.SendMessage(ctx, message, nil)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+func TestOllamaPoolPrefersHostWithModel(t *testing.T) {
+	a := newFakeOllama(t, []string{"llama3:latest"}, nil)
+	b := newFakeOllama(t, []string{"llama3:latest", "qwen:7b"}, []string{"qwen:7b"})
+	c := newFakeOllama(t, []string{"llama3:latest"}, []string{"llama3:latest"})
+	p := newTestPool(t, a, b, c)
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"qwen:7b", b.server.URL},
+		// An untagged name means the latest tag
+		{"llama3", c.server.URL},
+	}
+	for _, tt := range tests {
+		got, err := ask(context.Background(), p, tt.model)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.model, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s went to %s, want %s", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestOllamaPoolKeepsModelOnHostThatLoadedIt(t *testing.T) {
+	a := newFakeOllama(t, []string{"mistral:latest"}, nil)
+	b := newFakeOllama(t, []string{"mistral:latest"}, nil)
+	p := newTestPool(t, a, b)
+
+	first, err := ask(context.Background(), p, "mistral")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		got, err := ask(context.Background(), p, "mistral")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != first {
+			t.Fatalf("request %d went to %s, want %s", i, got, first)
+		}
+	}
+}
+
+func TestOllamaPoolSpreadsToLeastBusyHost(t *testing.T) {
+	hosts := []*fakeOllama{
+		newFakeOllama(t, nil, nil),
+		newFakeOllama(t, nil, nil),
+		newFakeOllama(t, nil, nil),
+	}
+	for _, h := range hosts {
+		h.set(func(f *fakeOllama) { f.delay = 100 * time.Millisecond })
+	}
+	p := newTestPool(t, hosts...)
+
+	// The model is on no host, so only the load decides
+	results := make(chan string, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			got, err := ask(context.Background(), p, "phi3")
+			if err != nil {
+				got = err.Error()
+			}
+			results <- got
+		}()
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		seen[<-results] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("concurrent requests went to %d hosts, want 3: %v", len(seen), seen)
+	}
+}
+
+func TestOllamaPoolEjectsAndReadmitsHosts(t *testing.T) {
+	a := newFakeOllama(t, []string{"llama3:latest"}, []string{"llama3:latest"})
+	b := newFakeOllama(t, []string{"llama3:latest"}, nil)
+	p := newTestPool(t, a, b)
+
+	// The loaded host fails, the request moves on to the other one
+	a.set(func(f *fakeOllama) { f.down = true })
+	got, err := ask(context.Background(), p, "llama3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != b.server.URL {
+		t.Errorf("failover went to %s, want %s", got, b.server.URL)
+	}
+	if healthy(p, 0) {
+		t.Error("failed host was not ejected")
+	}
+
+	// The next checks re-admit the host and report the model as loaded on
+	// it alone
+	a.set(func(f *fakeOllama) { f.down = false })
+	waitFor(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.hosts[0].healthy && !p.hosts[1].loaded["llama3:latest"]
+	})
+
+	got, err = ask(context.Background(), p, "llama3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != a.server.URL {
+		t.Errorf("re-admitted host with the loaded model was not used, got %s", got)
+	}
+}
+
+func TestOllamaPoolWithoutHealthyHosts(t *testing.T) {
+	a := newFakeOllama(t, nil, nil)
+	b := newFakeOllama(t, nil, nil)
+	p := newTestPool(t, a, b)
+
+	a.server.Close()
+	b.set(func(f *fakeOllama) { f.down = true })
+	waitFor(t, func() bool { return !healthy(p, 0) && !healthy(p, 1) })
+
+	_, err := ask(context.Background(), p, "llama3")
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("got %v, want ErrProviderUnavailable", err)
+	}
+}
+
+func TestOllamaPoolEmbeddings(t *testing.T) {
+	a := newFakeOllama(t, nil, nil)
+	b := newFakeOllama(t, nil, nil)
+	p := newTestPool(t, a, b)
+	a.set(func(f *fakeOllama) { f.down = true })
+	b.set(func(f *fakeOllama) { f.down = true })
+
+	embedder := p.EmbeddingService("nomic-embed-text")
+	if _, err := embedder.Embed(context.Background(), []string{"hello"}); err == nil {
+		t.Fatal("embedding succeeded without a healthy host")
+	}
+
+	b.set(func(f *fakeOllama) { f.down = false })
+	waitFor(t, func() bool { return healthy(p, 1) })
+	embeddings, err := embedder.Embed(context.Background(), []string{"hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embeddings) != 1 {
+		t.Errorf("got %d embeddings, want 1", len(embeddings))
+	}
+}
+
+func collectPull(t *testing.T, p *OllamaPool) []domain.ModelPullProgress {
+	t.Helper()
+	progress, err := p.PullModel(context.Background(), "llama3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updates []domain.ModelPullProgress
+	for update := range progress {
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+func TestOllamaPoolPullsOntoEveryHost(t *testing.T) {
+	a := newFakeOllama(t, nil, nil)
+	b := newFakeOllama(t, nil, nil)
+	p := newTestPool(t, a, b)
+
+	updates := collectPull(t, p)
+	successes := 0
+	for _, update := range updates {
+		if update.Err != nil {
+			t.Fatal(update.Err)
+		}
+		if update.Status == "success" {
+			successes++
+		}
+	}
+	if successes != 1 || updates[len(updates)-1].Status != "success" {
+		t.Errorf("want a single success at the end, got %+v", updates)
+	}
+
+	// A health check that started before the pull may briefly undo it
+	waitFor(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, h := range p.hosts {
+			if !h.models["llama3:latest"] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestOllamaPoolPullWithoutSuccessFails(t *testing.T) {
+	a := newFakeOllama(t, nil, nil)
+	b := newFakeOllama(t, nil, nil)
+	p := newTestPool(t, a, b)
+	b.set(func(f *fakeOllama) { f.pullStatuses = []string{"pulling manifest"} })
+
+	updates := collectPull(t, p)
+	last := updates[len(updates)-1]
+	if last.Err == nil {
+		t.Errorf("pull that ended early reported %+v", last)
+	}
+}
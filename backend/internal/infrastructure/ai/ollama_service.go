@@ -3,31 +3,50 @@ package ai
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/mariopavlov/nexus/backend/internal/core/domain"
 	"github.com/mariopavlov/nexus/backend/internal/core/ports"
 )
 
+// showConcurrency bounds the /api/show requests of a detailed listing.
+const showConcurrency = 4
+
 type OllamaService struct {
 	baseURL string
 	client  *http.Client
+
+	mu sync.Mutex
+	// shows caches /api/show by model digest, a changed model gets a new
+	// one. Digests missing from the latest /api/tags are pruned.
+	shows map[string]*ollamaShowResponse
 }
 
-func NewOllamaService(baseURL string) ports.AIModelService {
+// NewOllamaService uses client for every request, a nil client means no
+// timeouts.
+func NewOllamaService(baseURL string, client *http.Client) ports.AIModelService {
 	if baseURL == "" {
 		baseURL = os.Getenv("OLLAMA_URL")
 		if baseURL == "" {
 			baseURL = "http://localhost:11434"
 		}
 	}
+	if client == nil {
+		client = &http.Client{}
+	}
 	return &OllamaService{
 		baseURL: baseURL,
-		client:  &http.Client{},
+		client:  client,
+		shows:   make(map[string]*ollamaShowResponse),
 	}
 }
 
@@ -35,61 +54,154 @@ type ollamaRequest struct {
 	Model    string    `json:"model"`
 	Messages []message `json:"messages"`
 	Stream   bool      `json:"stream"`
+	// The domain options use Ollama's option names, so they are sent as-is
+	Options *domain.GenerationOptions `json:"options,omitempty"`
+	Tools   []tool                    `json:"tools,omitempty"`
+	// Format is "json" or a JSON schema
+	Format json.RawMessage `json:"format,omitempty"`
 }
 
 type message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+	// Images are base64 encoded without a data URL prefix
+	Images []string `json:"images,omitempty"`
+}
+
+// tool is the function tool format shared by Ollama and the OpenAI API.
+type tool struct {
+	Type     string       `json:"type"`
+	Function toolFunction `json:"function"`
+}
+
+type toolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type ollamaToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name string `json:"name"`
+		// Ollama sends the arguments as an object, not a string
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
 }
 
 type ollamaResponse struct {
-	Model     string `json:"model"`
-	CreatedAt string `json:"created_at"`
-	Message   struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	} `json:"message"`
-	DoneReason string `json:"done_reason"`
-	Done       bool   `json:"done"`
-	Error      string `json:"error,omitempty"`
+	Model      string  `json:"model"`
+	CreatedAt  string  `json:"created_at"`
+	Message    message `json:"message"`
+	DoneReason string  `json:"done_reason"`
+	Done       bool    `json:"done"`
+	Error      string  `json:"error,omitempty"`
+	// Timings are in nanoseconds and only sent with the final response
+	TotalDuration      int64 `json:"total_duration"`
+	LoadDuration       int64 `json:"load_duration"`
+	PromptEvalCount    int   `json:"prompt_eval_count"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration"`
+	EvalCount          int   `json:"eval_count"`
+	EvalDuration       int64 `json:"eval_duration"`
 }
 
-func (s *OllamaService) SendMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (*domain.Message, error) {
-	// Convert history to ollama messages format
-	messages := make([]message, 0, len(history)+2) // +2 for system message and current message
-	
-	// Add system message with formatting instructions
-
-	systemMessage := message{
-        Role: "system",
-        Content: `You are a helpful AI assistant. Please follow these guidelines:
-				- Use clear and concise language
-				- When sharing code, use proper markdown formatting:
-				- Inline code with single backticks: ` + "`code`" + `
-				- Code blocks with triple backticks and language: ` + "```language" + `
-				- Provide context-aware responses
-				- Maintain consistency in formatting
-				- Never use HTML tags for code formatting
-				- Always validate inputs and provide appropriate error messages`,
-    }
-
-	messages = append(messages, systemMessage)
+func (r *ollamaResponse) stats() *domain.GenerationStats {
+	return domain.NewGenerationStats(
+		r.PromptEvalCount,
+		r.EvalCount,
+		time.Duration(r.TotalDuration),
+		time.Duration(r.LoadDuration),
+		time.Duration(r.PromptEvalDuration),
+		time.Duration(r.EvalDuration),
+	)
+}
+
+func newTools(definitions []domain.ToolDefinition) []tool {
+	tools := make([]tool, len(definitions))
+	for i, d := range definitions {
+		tools[i] = tool{
+			Type: "function",
+			Function: toolFunction{
+				Name:        d.Name,
+				Description: d.Description,
+				Parameters:  d.Parameters,
+			},
+		}
+	}
+	return tools
+}
+
+func newFormat(format *domain.ResponseFormat) json.RawMessage {
+	if format == nil {
+		return nil
+	}
+	return format.Schema
+}
+
+func newOllamaMessage(m *domain.Message) message {
+	msg := message{
+		Role:     string(m.Role),
+		Content:  m.Content,
+		ToolName: m.ToolName,
+	}
+	for _, a := range m.Attachments {
+		if a.IsImage() && a.Data != nil {
+			msg.Images = append(msg.Images, base64.StdEncoding.EncodeToString(a.Data))
+		}
+	}
+	for _, call := range m.ToolCalls {
+		var tc ollamaToolCall
+		tc.ID = call.ID
+		tc.Function.Name = call.Name
+		tc.Function.Arguments = call.Arguments
+		if len(tc.Function.Arguments) == 0 {
+			tc.Function.Arguments = json.RawMessage("{}")
+		}
+		msg.ToolCalls = append(msg.ToolCalls, tc)
+	}
+	return msg
+}
+
+func newToolCalls(calls []ollamaToolCall) domain.ToolCalls {
+	if len(calls) == 0 {
+		return nil
+	}
+	toolCalls := make(domain.ToolCalls, len(calls))
+	for i, call := range calls {
+		toolCalls[i] = domain.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		}
+	}
+	return toolCalls
+}
+
+func (s *OllamaService) buildMessages(msg *domain.Message, history []*domain.Message) []message {
+	// Convert history to ollama messages format. The system prompt, if any,
+	// is part of the history handed over by the use case.
+	messages := make([]message, 0, len(history)+1) // +1 for current message
 
 	for _, m := range history {
-		messages = append(messages, message{
-			Role:    string(m.Role),
-			Content: m.Content,
-		})
+		messages = append(messages, newOllamaMessage(m))
 	}
-	messages = append(messages, message{
-		Role:    string(msg.Role),
-		Content: msg.Content,
-	})
+	messages = append(messages, newOllamaMessage(msg))
+
+	return messages
+}
+
+func (s *OllamaService) SendMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	messages := s.buildMessages(msg, history)
 
 	reqBody := ollamaRequest{
 		Model:    msg.Model,
 		Messages: messages,
 		Stream:   false,
+		Options:  msg.Options,
+		Tools:    newTools(msg.Tools),
+		Format:   newFormat(msg.Format),
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -97,8 +209,6 @@ func (s *OllamaService) SendMessage(ctx context.Context, msg *domain.Message, hi
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	fmt.Printf("Sending request to Ollama: %s\n", string(jsonBody))
-
 	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/api/chat", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -111,31 +221,174 @@ func (s *OllamaService) SendMessage(ctx context.Context, msg *domain.Message, hi
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.decodeError(resp)
+	}
+
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
-	fmt.Printf("Raw Ollama response: %s\n", string(respBody))
 
 	var ollamaResp ollamaResponse
 	if err := json.Unmarshal(respBody, &ollamaResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w, body: %s", err, string(respBody))
 	}
 
-	fmt.Printf("Decoded Ollama response: %+v\n", ollamaResp)
-
 	if ollamaResp.Error != "" {
-		return nil, fmt.Errorf("ollama error: %s", ollamaResp.Error)
+		return nil, bodyError(ollamaResp.Error)
 	}
 
-	if ollamaResp.Message.Content == "" {
+	// A tool call turn may come without any text
+	if ollamaResp.Message.Content == "" && len(ollamaResp.Message.ToolCalls) == 0 {
 		return nil, fmt.Errorf("empty response content from Ollama")
 	}
 
-	return domain.NewMessage(msg.ChatID, ollamaResp.Message.Content, domain.AssistantRole, msg.Model), nil
+	response := domain.NewMessage(msg.ChatID, ollamaResp.Message.Content, domain.AssistantRole, msg.Model)
+	response.Provider = "ollama"
+	response.ToolCalls = newToolCalls(ollamaResp.Message.ToolCalls)
+	response.Stats = ollamaResp.stats()
+	return response, nil
 }
 
-func (s *OllamaService) ListAvailableModels(ctx context.Context) ([]string, error) {
+func (s *OllamaService) StreamMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	reqBody := ollamaRequest{
+		Model:    msg.Model,
+		Messages: s.buildMessages(msg, history),
+		Stream:   true,
+		Options:  msg.Options,
+		Tools:    newTools(msg.Tools),
+		Format:   newFormat(msg.Format),
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/api/chat", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s.decodeError(resp)
+	}
+
+	chunks := make(chan domain.MessageChunk)
+	go func() {
+		defer close(chunks)
+		defer resp.Body.Close()
+
+		send := func(chunk domain.MessageChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// Ollama streams one JSON object per line. Tool calls arrive on
+		// their own lines and are reported together on the final chunk.
+		var toolCalls []ollamaToolCall
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			var ollamaResp ollamaResponse
+			if err := decoder.Decode(&ollamaResp); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				send(domain.MessageChunk{Err: fmt.Errorf("failed to decode stream: %w", err)})
+				return
+			}
+
+			if ollamaResp.Error != "" {
+				send(domain.MessageChunk{Err: bodyError(ollamaResp.Error)})
+				return
+			}
+
+			toolCalls = append(toolCalls, ollamaResp.Message.ToolCalls...)
+
+			chunk := domain.MessageChunk{
+				Content:    ollamaResp.Message.Content,
+				Done:       ollamaResp.Done,
+				DoneReason: ollamaResp.DoneReason,
+			}
+			if chunk.Done {
+				chunk.Provider = "ollama"
+				chunk.ToolCalls = newToolCalls(toolCalls)
+				chunk.Stats = ollamaResp.stats()
+			}
+			if !send(chunk) || chunk.Done {
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+type ollamaModelDetails struct {
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+type ollamaModel struct {
+	Name       string             `json:"name"`
+	ModifiedAt time.Time          `json:"modified_at"`
+	Size       int64              `json:"size"`
+	Digest     string             `json:"digest"`
+	Details    ollamaModelDetails `json:"details"`
+}
+
+type ollamaShowResponse struct {
+	Details    ollamaModelDetails     `json:"details"`
+	ModelInfo  map[string]interface{} `json:"model_info"`
+	ModifiedAt time.Time              `json:"modified_at"`
+	// Only reported by recent Ollama versions
+	Capabilities []string `json:"capabilities"`
+}
+
+// decodeError turns a non-2xx Ollama response into an *APIError.
+func (s *OllamaService) decodeError(resp *http.Response) error {
+	respBody, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{
+		Provider:   "ollama",
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(respBody)),
+	}
+
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error != "" {
+		apiErr.Message = errResp.Error
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// bodyError wraps an error Ollama reports in the body of a 200 response,
+// typically mid-stream. The request was accepted, so like the 500 Ollama
+// answers when a generation fails up front it means the model failed.
+func bodyError(message string) error {
+	return &APIError{Provider: "ollama", StatusCode: http.StatusInternalServerError, Message: message}
+}
+
+func (s *OllamaService) listTags(ctx context.Context) ([]ollamaModel, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/api/tags", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -147,6 +400,56 @@ func (s *OllamaService) ListAvailableModels(ctx context.Context) ([]string, erro
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.decodeError(resp)
+	}
+
+	var result struct {
+		Models []ollamaModel `json:"models"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	s.pruneShows(result.Models)
+	return result.Models, nil
+}
+
+// pruneShows drops the cached details of models that are no longer
+// installed, the listing reports every digest still in use.
+func (s *OllamaService) pruneShows(installed []ollamaModel) {
+	digests := make(map[string]bool, len(installed))
+	for _, model := range installed {
+		digests[model.Digest] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for digest := range s.shows {
+		if !digests[digest] {
+			delete(s.shows, digest)
+		}
+	}
+}
+
+// listRunning returns the names of the models loaded into memory.
+func (s *OllamaService) listRunning(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/api/ps", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s.decodeError(resp)
+	}
+
 	var result struct {
 		Models []struct {
 			Name string `json:"name"`
@@ -157,10 +460,139 @@ func (s *OllamaService) ListAvailableModels(ctx context.Context) ([]string, erro
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	models := make([]string, len(result.Models))
-	for i, model := range result.Models {
-		models[i] = model.Name
+	names := make([]string, len(result.Models))
+	for i, m := range result.Models {
+		names[i] = m.Name
+	}
+	return names, nil
+}
+
+func (s *OllamaService) showModel(ctx context.Context, name string) (*ollamaShowResponse, error) {
+	resp, err := s.doJSON(ctx, "POST", "/api/show", map[string]string{"model": name})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result ollamaShowResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+func newOllamaModelInfo(name string, details ollamaModelDetails) domain.ModelInfo {
+	return domain.ModelInfo{
+		Name:              name,
+		Provider:          "ollama",
+		Family:            details.Family,
+		Families:          details.Families,
+		Format:            details.Format,
+		ParameterSize:     details.ParameterSize,
+		QuantizationLevel: details.QuantizationLevel,
+	}
+}
+
+// applyShow copies the architecture specific fields of /api/show, which are
+// keyed by the architecture name (e.g. "llama.context_length").
+func applyShow(info *domain.ModelInfo, show *ollamaShowResponse) {
+	arch, _ := show.ModelInfo["general.architecture"].(string)
+	if count, ok := show.ModelInfo["general.parameter_count"].(float64); ok {
+		info.ParameterCount = int64(count)
+	}
+	if length, ok := show.ModelInfo[arch+".context_length"].(float64); ok {
+		info.ContextLength = int(length)
+	}
+	info.Capabilities = show.Capabilities
+}
+
+// ListModels asks /api/show for the details /api/tags does not report, such
+// as the context length, unless they are cached for the model's digest.
+func (s *OllamaService) ListModels(ctx context.Context, detailed bool) ([]domain.ModelInfo, error) {
+	tags, err := s.listTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]domain.ModelInfo, len(tags))
+	for i, tag := range tags {
+		models[i] = newOllamaModelInfo(tag.Name, tag.Details)
+		models[i].Size = tag.Size
+		models[i].Digest = tag.Digest
+		models[i].ModifiedAt = tag.ModifiedAt
+	}
+	if !detailed {
+		return models, nil
+	}
+
+	shows := make([]*ollamaShowResponse, len(tags))
+	s.mu.Lock()
+	for i, tag := range tags {
+		shows[i] = s.shows[tag.Digest]
 	}
+	s.mu.Unlock()
+
+	sem := make(chan struct{}, showConcurrency)
+	var wg sync.WaitGroup
+	for i := range tags {
+		if shows[i] != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			show, err := s.showModel(ctx, tags[i].Name)
+			if err != nil {
+				log.Printf("Failed to show model %s: %v", tags[i].Name, err)
+				return
+			}
+			shows[i] = show
+		}(i)
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	for i, show := range shows {
+		if show == nil {
+			continue
+		}
+		applyShow(&models[i], show)
+		if tags[i].Digest != "" {
+			s.shows[tags[i].Digest] = show
+		}
+	}
+	s.mu.Unlock()
 
 	return models, nil
 }
+
+func (s *OllamaService) GetModelInfo(ctx context.Context, name string) (*domain.ModelInfo, error) {
+	show, err := s.showModel(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := newOllamaModelInfo(name, show.Details)
+	info.ModifiedAt = show.ModifiedAt
+	applyShow(&info, show)
+
+	// Size and digest are only reported by /api/tags
+	tags, err := s.listTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if tag.Name == name {
+			info.Size = tag.Size
+			info.Digest = tag.Digest
+			break
+		}
+	}
+
+	return &info, nil
+}
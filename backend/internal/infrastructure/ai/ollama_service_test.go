@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+func TestOllamaServiceBodyErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The generation fails after the 200 went out
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`)
+		fmt.Fprintln(w, `{"error":"model runner has unexpectedly stopped"}`)
+	}))
+	defer server.Close()
+
+	s := NewOllamaService(server.URL, server.Client())
+	message := domain.NewMessage(domain.ChatID(uuid.New()), "hi", domain.UserRole, "llama3")
+
+	chunks, err := s.StreamMessage(context.Background(), message, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamErr error
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			streamErr = chunk.Err
+		}
+	}
+
+	var apiErr *APIError
+	if !errors.As(streamErr, &apiErr) || !errors.Is(streamErr, ErrModelFailed) {
+		t.Errorf("stream failed with %v, want an APIError for a failed model", streamErr)
+	}
+	if isTransient(streamErr) {
+		t.Error("a stopped model runner is retried")
+	}
+}
+
+func TestOllamaServiceBodyErrorWithoutStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":"model is still loading"}`)
+	}))
+	defer server.Close()
+
+	s := NewOllamaService(server.URL, server.Client())
+	message := domain.NewMessage(domain.ChatID(uuid.New()), "hi", domain.UserRole, "llama3")
+
+	_, err := s.SendMessage(context.Background(), message, nil)
+	if !errors.Is(err, ErrModelFailed) {
+		t.Errorf("got %v, want ErrModelFailed", err)
+	}
+	if !isTransient(err) {
+		t.Error("a loading model is not retried")
+	}
+}
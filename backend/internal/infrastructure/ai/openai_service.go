@@ -0,0 +1,523 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// OpenAIService talks to any server implementing the OpenAI
+// /v1/chat/completions and /v1/models endpoints (OpenAI, vLLM, llama.cpp, ...).
+type OpenAIService struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewOpenAIService expects baseURL to include the API version prefix,
+// e.g. "http://vllm:8000/v1". A nil client means no timeouts.
+func NewOpenAIService(baseURL, apiKey string, client *http.Client) ports.AIModelService {
+	if baseURL == "" {
+		baseURL = os.Getenv("OPENAI_BASE_URL")
+		if baseURL == "" {
+			baseURL = "https://api.openai.com/v1"
+		}
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &OpenAIService{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  client,
+	}
+}
+
+type openAIRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Stream         bool                  `json:"stream"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	Seed           *int                  `json:"seed,omitempty"`
+	MaxTokens      *int                  `json:"max_tokens,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	Tools          []tool                `json:"tools,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	// Not part of the OpenAI API but accepted by vLLM and llama.cpp
+	TopK              *int     `json:"top_k,omitempty"`
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+func newOpenAIResponseFormat(format *domain.ResponseFormat) *openAIResponseFormat {
+	switch {
+	case format == nil:
+		return nil
+	case format.IsSchema():
+		return &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openAIJSONSchema{Name: "response", Schema: format.Schema},
+		}
+	default:
+		return &openAIResponseFormat{Type: "json_object"}
+	}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+	// Images are data URLs, sent as content parts by MarshalJSON
+	Images []string `json:"-"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON switches the content to the list of parts the API requires
+// once images are attached.
+func (m openAIMessage) MarshalJSON() ([]byte, error) {
+	type plain openAIMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+
+	parts := []openAIContentPart{{Type: "text", Text: m.Content}}
+	for _, url := range m.Images {
+		parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+	}
+	return json.Marshal(struct {
+		plain
+		Content []openAIContentPart `json:"content"`
+	}{plain(m), parts})
+}
+
+type openAIToolCall struct {
+	// Index identifies the call a streamed delta belongs to
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name string `json:"name,omitempty"`
+		// The arguments are a JSON document encoded as a string
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	// Sent on the last chunk of a stream when include_usage is set
+	Usage *openAIUsage `json:"usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// newOpenAIStats builds stats from the token usage; the API reports no
+// timings, so only the wall-clock duration of the request is known.
+func newOpenAIStats(usage *openAIUsage, started time.Time) *domain.GenerationStats {
+	if usage == nil {
+		usage = &openAIUsage{}
+	}
+	return domain.NewGenerationStats(usage.PromptTokens, usage.CompletionTokens, time.Since(started), 0, 0, 0)
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"`
+	} `json:"error"`
+}
+
+func newOpenAIMessage(m *domain.Message) openAIMessage {
+	msg := openAIMessage{
+		Role:       string(m.Role),
+		Content:    m.Content,
+		ToolCallID: m.ToolCallID,
+	}
+	for _, a := range m.Attachments {
+		if a.IsImage() && a.Data != nil {
+			msg.Images = append(msg.Images, "data:"+a.ContentType+";base64,"+base64.StdEncoding.EncodeToString(a.Data))
+		}
+	}
+	for _, call := range m.ToolCalls {
+		var tc openAIToolCall
+		tc.ID = call.ID
+		tc.Type = "function"
+		tc.Function.Name = call.Name
+		tc.Function.Arguments = string(call.Arguments)
+		if tc.Function.Arguments == "" {
+			tc.Function.Arguments = "{}"
+		}
+		msg.ToolCalls = append(msg.ToolCalls, tc)
+	}
+	return msg
+}
+
+func newOpenAIToolCalls(calls []openAIToolCall) domain.ToolCalls {
+	if len(calls) == 0 {
+		return nil
+	}
+	toolCalls := make(domain.ToolCalls, len(calls))
+	for i, call := range calls {
+		// Models occasionally produce invalid JSON, keep it as a string so
+		// the tool can report the problem instead of failing the turn
+		arguments := json.RawMessage(call.Function.Arguments)
+		if call.Function.Arguments == "" {
+			arguments = json.RawMessage("{}")
+		} else if !json.Valid(arguments) {
+			arguments, _ = json.Marshal(call.Function.Arguments)
+		}
+		toolCalls[i] = domain.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: arguments,
+		}
+	}
+	return toolCalls
+}
+
+// mergeToolCallDeltas assembles streamed tool calls, whose arguments arrive
+// in fragments keyed by index.
+func mergeToolCallDeltas(calls []openAIToolCall, deltas []openAIToolCall) []openAIToolCall {
+	for _, delta := range deltas {
+		index := len(calls)
+		if delta.Index != nil {
+			index = *delta.Index
+		}
+		for len(calls) <= index {
+			calls = append(calls, openAIToolCall{})
+		}
+
+		call := &calls[index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+	return calls
+}
+
+func (s *OpenAIService) buildMessages(msg *domain.Message, history []*domain.Message) []openAIMessage {
+	messages := make([]openAIMessage, 0, len(history)+1)
+	for _, m := range history {
+		messages = append(messages, newOpenAIMessage(m))
+	}
+	messages = append(messages, newOpenAIMessage(msg))
+
+	return messages
+}
+
+func (s *OpenAIService) newChatRequest(msg *domain.Message, history []*domain.Message, stream bool) openAIRequest {
+	req := openAIRequest{
+		Model:    msg.Model,
+		Messages: s.buildMessages(msg, history),
+		Stream:   stream,
+		Tools:    newTools(msg.Tools),
+		// Not every compatible server supports json_schema, the use case
+		// validates the answer regardless
+		ResponseFormat: newOpenAIResponseFormat(msg.Format),
+	}
+	if stream {
+		req.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+
+	// num_ctx has no equivalent, the server decides the context size
+	if o := msg.Options; o != nil {
+		req.Temperature = o.Temperature
+		req.TopP = o.TopP
+		req.Seed = o.Seed
+		req.MaxTokens = o.NumPredict
+		req.Stop = o.Stop
+		req.TopK = o.TopK
+		req.RepetitionPenalty = o.RepeatPenalty
+	}
+	return req
+}
+
+func (s *OpenAIService) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewBuffer(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+	return req, nil
+}
+
+func (s *OpenAIService) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, s.decodeError(resp)
+	}
+	return resp, nil
+}
+
+func (s *OpenAIService) decodeError(resp *http.Response) error {
+	respBody, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{
+		Provider:   "openai",
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(respBody)),
+	}
+
+	var errResp openAIErrorResponse
+	if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+		apiErr.Message = errResp.Error.Message
+		apiErr.Type = errResp.Error.Type
+		if errResp.Error.Code != nil {
+			apiErr.Code = fmt.Sprint(errResp.Error.Code)
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+func (s *OpenAIService) SendMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	started := time.Now()
+	req, err := s.newRequest(ctx, "POST", "/chat/completions", s.newChatRequest(msg, history, false))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var openAIResp openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		return nil, fmt.Errorf("empty response content from OpenAI-compatible provider")
+	}
+	choice := openAIResp.Choices[0].Message
+	if choice.Content == "" && len(choice.ToolCalls) == 0 {
+		return nil, fmt.Errorf("empty response content from OpenAI-compatible provider")
+	}
+
+	response := domain.NewMessage(msg.ChatID, choice.Content, domain.AssistantRole, msg.Model)
+	response.Provider = "openai"
+	response.ToolCalls = newOpenAIToolCalls(choice.ToolCalls)
+	response.Stats = newOpenAIStats(openAIResp.Usage, started)
+	return response, nil
+}
+
+func (s *OpenAIService) StreamMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	started := time.Now()
+	req, err := s.newRequest(ctx, "POST", "/chat/completions", s.newChatRequest(msg, history, true))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan domain.MessageChunk)
+	go func() {
+		defer close(chunks)
+		defer resp.Body.Close()
+
+		send := func(chunk domain.MessageChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// The stream is a sequence of "data: {...}" lines terminated by "data: [DONE]"
+		var finishReason string
+		var toolCalls []openAIToolCall
+		var usage *openAIUsage
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+			if data == "[DONE]" {
+				send(domain.MessageChunk{
+					Done:       true,
+					DoneReason: finishReason,
+					Provider:   "openai",
+					ToolCalls:  newOpenAIToolCalls(toolCalls),
+					Stats:      newOpenAIStats(usage, started),
+				})
+				return
+			}
+
+			var openAIResp openAIResponse
+			if err := json.Unmarshal([]byte(data), &openAIResp); err != nil {
+				send(domain.MessageChunk{Err: fmt.Errorf("failed to decode stream: %w", err)})
+				return
+			}
+			if openAIResp.Usage != nil {
+				usage = openAIResp.Usage
+			}
+			if len(openAIResp.Choices) == 0 {
+				continue
+			}
+
+			choice := openAIResp.Choices[0]
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			toolCalls = mergeToolCallDeltas(toolCalls, choice.Delta.ToolCalls)
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if !send(domain.MessageChunk{Content: choice.Delta.Content}) {
+				return
+			}
+		}
+
+		err := scanner.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		send(domain.MessageChunk{Err: fmt.Errorf("failed to read stream: %w", err)})
+	}()
+
+	return chunks, nil
+}
+
+type openAIModel struct {
+	ID      string `json:"id"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+	// Reported by vLLM only
+	MaxModelLen int `json:"max_model_len"`
+}
+
+// ListModels reports the same fields with or without details, the listing is
+// all an OpenAI-compatible server offers.
+func (s *OpenAIService) ListModels(ctx context.Context, detailed bool) ([]domain.ModelInfo, error) {
+	req, err := s.newRequest(ctx, "GET", "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []openAIModel `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]domain.ModelInfo, len(result.Data))
+	for i, model := range result.Data {
+		models[i] = domain.ModelInfo{
+			Name:          model.ID,
+			Provider:      "openai",
+			ContextLength: model.MaxModelLen,
+		}
+		if model.Created > 0 {
+			models[i].ModifiedAt = time.Unix(model.Created, 0).UTC()
+		}
+	}
+
+	return models, nil
+}
+
+// GetModelInfo looks the model up in the listing because not every
+// OpenAI-compatible server implements GET /models/{id}.
+func (s *OpenAIService) GetModelInfo(ctx context.Context, name string) (*domain.ModelInfo, error) {
+	models, err := s.ListModels(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range models {
+		if models[i].Name == name {
+			return &models[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrModelNotFound, name)
+}
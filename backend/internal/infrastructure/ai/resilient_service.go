@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type ResilienceConfig struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// FailureThreshold consecutive failures open the circuit breaker
+	FailureThreshold int
+	// Cooldown is how long the breaker stays open before probing again
+	Cooldown time.Duration
+}
+
+func (c *ResilienceConfig) applyDefaults() {
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = 2
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 500 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Second
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 5
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = 30 * time.Second
+	}
+}
+
+// ResilientService retries transient failures of a provider with
+// exponential backoff and fails fast through a circuit breaker while the
+// provider is unhealthy. Errors unwrap to ErrProviderUnavailable or
+// ErrProviderTimeout when the backend is at fault.
+type ResilientService struct {
+	name    string
+	service ports.AIModelService
+	config  ResilienceConfig
+	breaker *circuitBreaker
+}
+
+// NewResilientService wraps service; a negative MaxRetries disables retries.
+func NewResilientService(name string, service ports.AIModelService, config ResilienceConfig) ports.AIModelService {
+	config.applyDefaults()
+	return &ResilientService{
+		name:    name,
+		service: service,
+		config:  config,
+		breaker: newCircuitBreaker(config.FailureThreshold, config.Cooldown),
+	}
+}
+
+// record feeds the outcome of a call to the circuit breaker. Errors that
+// come from the request or the model itself, like an unknown model or one
+// that ran out of memory, still prove the backend is up.
+func (s *ResilientService) record(err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		s.breaker.release()
+	case errors.Is(err, ErrProviderUnavailable) || errors.Is(err, ErrProviderTimeout):
+		s.breaker.failure()
+	default:
+		s.breaker.success()
+	}
+}
+
+func (s *ResilientService) call(ctx context.Context, op func() error) error {
+	backoff := s.config.InitialBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if !s.breaker.allow() {
+			// A retry cut short by the breaker reports what actually failed
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: circuit breaker for %s is open", ErrProviderUnavailable, s.name)
+		}
+
+		err = classify(op())
+		s.record(err)
+		if err == nil || !isTransient(err) || attempt >= s.config.MaxRetries {
+			return err
+		}
+
+		// Jitter keeps clients that failed together from retrying together
+		wait := time.Duration(rand.Int63n(int64(backoff))) + backoff/2
+		log.Printf("Retrying %s in %s after attempt %d failed: %v", s.name, wait, attempt+1, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+
+		backoff *= 2
+		if backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+func (s *ResilientService) SendMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	var response *domain.Message
+	err := s.call(ctx, func() error {
+		var err error
+		response, err = s.service.SendMessage(ctx, msg, history)
+		return err
+	})
+	return response, err
+}
+
+// StreamMessage only retries starting the stream; once chunks have been
+// delivered a failure is reported to the caller.
+func (s *ResilientService) StreamMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	var stream <-chan domain.MessageChunk
+	err := s.call(ctx, func() error {
+		var err error
+		stream, err = s.service.StreamMessage(ctx, msg, history)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan domain.MessageChunk)
+	go func() {
+		defer close(chunks)
+		for chunk := range stream {
+			if chunk.Err != nil {
+				chunk.Err = classify(chunk.Err)
+				s.record(chunk.Err)
+			}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+func (s *ResilientService) ListModels(ctx context.Context, detailed bool) ([]domain.ModelInfo, error) {
+	var models []domain.ModelInfo
+	err := s.call(ctx, func() error {
+		var err error
+		models, err = s.service.ListModels(ctx, detailed)
+		return err
+	})
+	return models, err
+}
+
+func (s *ResilientService) GetModelInfo(ctx context.Context, name string) (*domain.ModelInfo, error) {
+	var info *domain.ModelInfo
+	err := s.call(ctx, func() error {
+		var err error
+		info, err = s.service.GetModelInfo(ctx, name)
+		return err
+	})
+	return info, err
+}
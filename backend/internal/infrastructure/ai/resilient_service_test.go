@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// failingService fails its first calls with the given errors, then answers.
+type failingService struct {
+	ports.AIModelService
+	errs  []error
+	calls int
+}
+
+func (f *failingService) SendMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return domain.NewMessage(message.ChatID, "ok", domain.AssistantRole, message.Model), nil
+}
+
+func TestResilientServiceRetries(t *testing.T) {
+	unavailable := &APIError{Provider: "test", StatusCode: 503, Message: "overloaded"}
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"transient failures are retried", []error{unavailable, unavailable}, 3, nil},
+		{"retries run out", []error{unavailable, unavailable, unavailable}, 3, ErrProviderUnavailable},
+		{"other failures are not retried", []error{&APIError{Provider: "test", StatusCode: 404}}, 1, ErrModelNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &failingService{errs: tt.errs}
+			s := NewResilientService("test", fake, ResilienceConfig{MaxRetries: 2, InitialBackoff: time.Millisecond, FailureThreshold: 10})
+			message := domain.NewMessage(domain.ChatID(uuid.New()), "hi", domain.UserRole, "llama3")
+			_, err := s.SendMessage(context.Background(), message, nil)
+			if tt.wantErr == nil && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+			if fake.calls != tt.wantCalls {
+				t.Errorf("made %d calls, want %d", fake.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestResilientServiceOpensBreaker(t *testing.T) {
+	unavailable := &APIError{Provider: "test", StatusCode: 503, Message: "overloaded"}
+	fake := &failingService{errs: []error{unavailable, unavailable}}
+	s := NewResilientService("test", fake, ResilienceConfig{MaxRetries: -1, FailureThreshold: 2, Cooldown: time.Minute})
+	message := domain.NewMessage(domain.ChatID(uuid.New()), "hi", domain.UserRole, "llama3")
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.SendMessage(context.Background(), message, nil); !errors.Is(err, ErrProviderUnavailable) {
+			t.Fatalf("call %d: got %v, want ErrProviderUnavailable", i, err)
+		}
+	}
+	// The open breaker answered the last call without reaching the backend
+	if fake.calls != 2 {
+		t.Errorf("backend got %d calls, want 2", fake.calls)
+	}
+}
@@ -0,0 +1,203 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// Provider is a named model backend registered with the router.
+type Provider struct {
+	Name    string
+	Service ports.AIModelService
+}
+
+// RoutingRule sends models whose name starts with Prefix to Provider when the
+// model ID is not already namespaced, e.g. {"gpt-", "openai"}.
+type RoutingRule struct {
+	Prefix   string
+	Provider string
+}
+
+// RouterService composes several providers behind a single AIModelService.
+// Model IDs are namespaced as "<provider>/<model>"; un-namespaced IDs are
+// resolved through the routing rules and fall back to the first provider.
+type RouterService struct {
+	providers []Provider
+	byName    map[string]ports.AIModelService
+	rules     []RoutingRule
+}
+
+func NewRouterService(providers []Provider, rules []RoutingRule) ports.AIModelService {
+	byName := make(map[string]ports.AIModelService, len(providers))
+	for _, p := range providers {
+		byName[p.Name] = p.Service
+	}
+	return &RouterService{
+		providers: providers,
+		byName:    byName,
+		rules:     rules,
+	}
+}
+
+// ParseRoutingRules parses a "prefix=provider,prefix=provider" table.
+func ParseRoutingRules(spec string) ([]RoutingRule, error) {
+	var rules []RoutingRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, provider, ok := strings.Cut(entry, "=")
+		if !ok || prefix == "" || provider == "" {
+			return nil, fmt.Errorf("invalid routing rule %q, expected prefix=provider", entry)
+		}
+		rules = append(rules, RoutingRule{
+			Prefix:   strings.TrimSpace(prefix),
+			Provider: strings.TrimSpace(provider),
+		})
+	}
+	return rules, nil
+}
+
+// resolve returns the provider name, its service and the model ID the
+// provider itself understands.
+func (r *RouterService) resolve(model string) (string, ports.AIModelService, string, error) {
+	if name, upstream, ok := strings.Cut(model, "/"); ok {
+		if svc, exists := r.byName[name]; exists {
+			return name, svc, upstream, nil
+		}
+	}
+
+	// Longest matching prefix wins
+	var match *RoutingRule
+	for i, rule := range r.rules {
+		if strings.HasPrefix(model, rule.Prefix) && (match == nil || len(rule.Prefix) > len(match.Prefix)) {
+			match = &r.rules[i]
+		}
+	}
+	if match != nil {
+		svc, exists := r.byName[match.Provider]
+		if !exists {
+			return "", nil, "", fmt.Errorf("routing rule %q points to unknown provider %q", match.Prefix, match.Provider)
+		}
+		return match.Provider, svc, model, nil
+	}
+
+	if len(r.providers) == 0 {
+		return "", nil, "", fmt.Errorf("no providers configured")
+	}
+	return r.providers[0].Name, r.providers[0].Service, model, nil
+}
+
+func (r *RouterService) SendMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	name, svc, upstream, err := r.resolve(msg.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	forwarded := *msg
+	forwarded.Model = upstream
+
+	response, err := svc.SendMessage(ctx, &forwarded, history)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+
+	response.Model = msg.Model
+	response.Provider = name
+	return response, nil
+}
+
+func (r *RouterService) StreamMessage(ctx context.Context, msg *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	name, svc, upstream, err := r.resolve(msg.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	forwarded := *msg
+	forwarded.Model = upstream
+
+	stream, err := svc.StreamMessage(ctx, &forwarded, history)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+
+	chunks := make(chan domain.MessageChunk)
+	go func() {
+		defer close(chunks)
+		for chunk := range stream {
+			if chunk.Err != nil {
+				chunk.Err = fmt.Errorf("provider %s: %w", name, chunk.Err)
+			}
+			if chunk.Done {
+				chunk.Provider = name
+			}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+// ListModels aggregates the models of every provider. A failing provider is
+// logged and skipped unless all of them fail.
+func (r *RouterService) ListModels(ctx context.Context, detailed bool) ([]domain.ModelInfo, error) {
+	results := make([][]domain.ModelInfo, len(r.providers))
+	errs := make([]error, len(r.providers))
+
+	var wg sync.WaitGroup
+	for i, p := range r.providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			results[i], errs[i] = p.Service.ListModels(ctx, detailed)
+		}(i, p)
+	}
+	wg.Wait()
+
+	var models []domain.ModelInfo
+	failed := 0
+	for i, p := range r.providers {
+		if errs[i] != nil {
+			log.Printf("Failed to list models from provider %s: %v", p.Name, errs[i])
+			failed++
+			continue
+		}
+		for _, model := range results[i] {
+			model.Name = p.Name + "/" + model.Name
+			model.Provider = p.Name
+			models = append(models, model)
+		}
+	}
+
+	if failed > 0 && failed == len(r.providers) {
+		return nil, fmt.Errorf("failed to list models from all providers: %w", errs[0])
+	}
+	return models, nil
+}
+
+func (r *RouterService) GetModelInfo(ctx context.Context, name string) (*domain.ModelInfo, error) {
+	provider, svc, upstream, err := r.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := svc.GetModelInfo(ctx, upstream)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", provider, err)
+	}
+
+	info.Name = provider + "/" + upstream
+	info.Provider = provider
+	return info, nil
+}
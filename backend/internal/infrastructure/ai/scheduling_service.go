@@ -0,0 +1,309 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type SchedulerConfig struct {
+	// Concurrency is the number of generations a model runs at once
+	Concurrency int
+	// ModelConcurrency overrides Concurrency for single models
+	ModelConcurrency map[string]int
+	// QueueSize is the number of requests that may wait for each model
+	QueueSize int
+}
+
+func (c *SchedulerConfig) applyDefaults() {
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 32
+	}
+}
+
+// ParseModelConcurrency parses a "model=limit,model=limit" table.
+func ParseModelConcurrency(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, value, ok := strings.Cut(entry, "=")
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || model == "" || err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid model concurrency %q, expected model=limit", entry)
+		}
+		limits[strings.TrimSpace(model)] = limit
+	}
+	return limits, nil
+}
+
+// priorities lists the priority classes in the order they are served.
+var priorities = []domain.Priority{domain.PriorityHigh, domain.PriorityNormal, domain.PriorityLow}
+
+type waiter struct {
+	priority domain.Priority
+	// ready is closed once the waiter holds a slot
+	ready   chan struct{}
+	granted bool
+	// moved is signalled when the waiter may have moved up the queue
+	moved chan struct{}
+}
+
+// modelQueue holds the running and waiting generations of one model.
+type modelQueue struct {
+	limit   int
+	running int
+	waiting map[domain.Priority][]*waiter
+}
+
+func (q *modelQueue) len() int {
+	n := 0
+	for _, waiters := range q.waiting {
+		n += len(waiters)
+	}
+	return n
+}
+
+// position returns how many waiters are served before w, plus one.
+func (q *modelQueue) position(w *waiter) int {
+	position := 1
+	for _, p := range priorities {
+		for _, other := range q.waiting[p] {
+			if other == w {
+				return position
+			}
+			position++
+		}
+	}
+	return 0
+}
+
+func (q *modelQueue) remove(w *waiter) {
+	waiters := q.waiting[w.priority]
+	for i, other := range waiters {
+		if other == w {
+			q.waiting[w.priority] = append(waiters[:i], waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// next pops the first waiter of the most urgent class.
+func (q *modelQueue) next() *waiter {
+	for _, p := range priorities {
+		if waiters := q.waiting[p]; len(waiters) > 0 {
+			q.waiting[p] = waiters[1:]
+			return waiters[0]
+		}
+	}
+	return nil
+}
+
+func (q *modelQueue) idle() bool {
+	return q.running == 0 && q.len() == 0
+}
+
+func (q *modelQueue) notifyMoved() {
+	for _, waiters := range q.waiting {
+		for _, w := range waiters {
+			select {
+			case w.moved <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// SchedulingService limits how many generations run on each model at once.
+// Requests beyond the limit wait in a bounded queue, served by priority and
+// then in arrival order; a full queue rejects them with ErrQueueFull.
+type SchedulingService struct {
+	ports.AIModelService
+	config SchedulerConfig
+
+	mu     sync.Mutex
+	queues map[string]*modelQueue
+}
+
+func NewSchedulingService(service ports.AIModelService, config SchedulerConfig) ports.AIModelService {
+	config.applyDefaults()
+	return &SchedulingService{
+		AIModelService: service,
+		config:         config,
+		queues:         make(map[string]*modelQueue),
+	}
+}
+
+// enqueue takes a slot for the model if one is free, otherwise it queues a
+// waiter. A nil waiter means the slot is already held.
+func (s *SchedulingService) enqueue(model string, priority domain.Priority) (*waiter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.queues[model]
+	if !ok {
+		limit := s.config.Concurrency
+		if l, ok := s.config.ModelConcurrency[model]; ok {
+			limit = l
+		}
+		q = &modelQueue{limit: limit, waiting: make(map[domain.Priority][]*waiter)}
+		s.queues[model] = q
+	}
+
+	if q.running < q.limit && q.len() == 0 {
+		q.running++
+		return nil, nil
+	}
+	if q.len() >= s.config.QueueSize {
+		return nil, fmt.Errorf("%w: %d requests are waiting for %s", ErrQueueFull, q.len(), model)
+	}
+
+	w := &waiter{priority: priority, ready: make(chan struct{}), moved: make(chan struct{}, 1)}
+	q.waiting[priority] = append(q.waiting[priority], w)
+	// A more urgent request moves the ones behind it back
+	q.notifyMoved()
+	return w, nil
+}
+
+// wait blocks until w holds a slot, reporting its queue position whenever
+// it changes.
+func (s *SchedulingService) wait(ctx context.Context, model string, w *waiter, report func(position int)) error {
+	last := 0
+	for {
+		s.mu.Lock()
+		position := s.queues[model].position(w)
+		s.mu.Unlock()
+		if position > 0 && position != last {
+			report(position)
+			last = position
+		}
+
+		select {
+		case <-w.ready:
+			return nil
+		case <-w.moved:
+		case <-ctx.Done():
+			s.mu.Lock()
+			granted := w.granted
+			if !granted {
+				q := s.queues[model]
+				q.remove(w)
+				q.notifyMoved()
+				s.dropIdle(model, q)
+			}
+			s.mu.Unlock()
+			if granted {
+				s.release(model)
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// release hands the slot to the next waiter.
+func (s *SchedulingService) release(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := s.queues[model]
+	q.running--
+	if w := q.next(); w != nil {
+		q.running++
+		w.granted = true
+		close(w.ready)
+		q.notifyMoved()
+	}
+	s.dropIdle(model, q)
+}
+
+// dropIdle forgets the queue of a model nothing runs on or waits for, so
+// requests for ever new model names do not pile up queues. The caller must
+// hold s.mu.
+func (s *SchedulingService) dropIdle(model string, q *modelQueue) {
+	if q.idle() {
+		delete(s.queues, model)
+	}
+}
+
+func (s *SchedulingService) SendMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	w, err := s.enqueue(message.Model, domain.PriorityFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if w != nil {
+		if err := s.wait(ctx, message.Model, w, func(int) {}); err != nil {
+			return nil, err
+		}
+	}
+	defer s.release(message.Model)
+
+	return s.AIModelService.SendMessage(ctx, message, history)
+}
+
+// StreamMessage holds the model's slot until the stream ends. A queued
+// request returns at once and reports its queue position in chunks.
+func (s *SchedulingService) StreamMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	w, err := s.enqueue(message.Model, domain.PriorityFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var stream <-chan domain.MessageChunk
+	if w == nil {
+		// Starting right away keeps start failures synchronous
+		stream, err = s.AIModelService.StreamMessage(ctx, message, history)
+		if err != nil {
+			s.release(message.Model)
+			return nil, err
+		}
+	}
+
+	chunks := make(chan domain.MessageChunk)
+	go func() {
+		defer close(chunks)
+
+		send := func(chunk domain.MessageChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if w != nil {
+			err := s.wait(ctx, message.Model, w, func(position int) {
+				send(domain.MessageChunk{QueuePosition: position})
+			})
+			if err != nil {
+				return
+			}
+
+			stream, err = s.AIModelService.StreamMessage(ctx, message, history)
+			if err != nil {
+				s.release(message.Model)
+				send(domain.MessageChunk{Err: err})
+				return
+			}
+		}
+		defer s.release(message.Model)
+
+		for chunk := range stream {
+			if !send(chunk) {
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
@@ -0,0 +1,247 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// blockingService reports every generation it starts and holds it until
+// the test releases it.
+type blockingService struct {
+	ports.AIModelService
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingService() *blockingService {
+	return &blockingService{started: make(chan string, 16), release: make(chan struct{})}
+}
+
+func (b *blockingService) SendMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (*domain.Message, error) {
+	b.started <- message.Content
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return domain.NewMessage(message.ChatID, message.Content, domain.AssistantRole, message.Model), nil
+}
+
+func (b *blockingService) StreamMessage(ctx context.Context, message *domain.Message, history []*domain.Message) (<-chan domain.MessageChunk, error) {
+	b.started <- message.Content
+	chunks := make(chan domain.MessageChunk, 1)
+	go func() {
+		defer close(chunks)
+		<-b.release
+		chunks <- domain.MessageChunk{Content: message.Content, Done: true}
+	}()
+	return chunks, nil
+}
+
+func newTestScheduler(config SchedulerConfig) (*SchedulingService, *blockingService) {
+	fake := newBlockingService()
+	return NewSchedulingService(fake, config).(*SchedulingService), fake
+}
+
+func generate(s *SchedulingService, priority domain.Priority, model, content string) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		ctx := domain.WithPriority(context.Background(), priority)
+		_, err := s.SendMessage(ctx, domain.NewMessage(domain.ChatID(uuid.New()), content, domain.UserRole, model), nil)
+		done <- err
+	}()
+	return done
+}
+
+// waiting returns how many requests wait for the model.
+func waiting(s *SchedulingService, model string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.queues[model]; ok {
+		return q.len()
+	}
+	return 0
+}
+
+func TestSchedulingServiceServesByPriority(t *testing.T) {
+	s, fake := newTestScheduler(SchedulerConfig{Concurrency: 1})
+
+	var done []<-chan error
+	done = append(done, generate(s, domain.PriorityNormal, "llama3", "first"))
+	if got := <-fake.started; got != "first" {
+		t.Fatalf("started %s, want first", got)
+	}
+	// Each request is queued before the next one arrives
+	for i, request := range []struct {
+		priority domain.Priority
+		content  string
+	}{
+		{domain.PriorityLow, "low"},
+		{domain.PriorityNormal, "normal 1"},
+		{domain.PriorityHigh, "high"},
+		{domain.PriorityNormal, "normal 2"},
+	} {
+		done = append(done, generate(s, request.priority, "llama3", request.content))
+		waitFor(t, func() bool { return waiting(s, "llama3") == i+1 })
+	}
+
+	for _, want := range []string{"high", "normal 1", "normal 2", "low"} {
+		fake.release <- struct{}{}
+		if got := <-fake.started; got != want {
+			t.Errorf("started %s, want %s", got, want)
+		}
+	}
+	fake.release <- struct{}{}
+	for _, d := range done {
+		if err := <-d; err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Nothing runs or waits any more, so the model's queue is gone
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queues) != 0 {
+		t.Errorf("%d idle queues were kept", len(s.queues))
+	}
+}
+
+func TestSchedulingServiceRejectsWhenQueueIsFull(t *testing.T) {
+	s, fake := newTestScheduler(SchedulerConfig{Concurrency: 1, QueueSize: 1})
+
+	first := generate(s, domain.PriorityNormal, "llama3", "first")
+	<-fake.started
+	second := generate(s, domain.PriorityNormal, "llama3", "second")
+	waitFor(t, func() bool { return waiting(s, "llama3") == 1 })
+
+	// The handlers answer ErrQueueFull with 429
+	if err := <-generate(s, domain.PriorityHigh, "llama3", "third"); !errors.Is(err, domain.ErrQueueFull) {
+		t.Errorf("got %v, want ErrQueueFull", err)
+	}
+	// Other models have queues of their own
+	other := generate(s, domain.PriorityNormal, "mistral", "other")
+	if got := <-fake.started; got != "other" {
+		t.Errorf("started %s, want other", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		fake.release <- struct{}{}
+	}
+	for _, d := range []<-chan error{first, second, other} {
+		if err := <-d; err != nil {
+			t.Error(err)
+		}
+	}
+	if got := <-fake.started; got != "second" {
+		t.Errorf("started %s, want second", got)
+	}
+}
+
+func TestSchedulingServiceModelConcurrency(t *testing.T) {
+	s, fake := newTestScheduler(SchedulerConfig{Concurrency: 1, ModelConcurrency: map[string]int{"llama3": 2}})
+
+	var done []<-chan error
+	for _, content := range []string{"a", "b", "c"} {
+		done = append(done, generate(s, domain.PriorityNormal, "llama3", content))
+	}
+	<-fake.started
+	<-fake.started
+	waitFor(t, func() bool { return waiting(s, "llama3") == 1 })
+
+	select {
+	case got := <-fake.started:
+		t.Fatalf("%s started beyond the model's limit", got)
+	default:
+	}
+
+	fake.release <- struct{}{}
+	<-fake.started
+	fake.release <- struct{}{}
+	fake.release <- struct{}{}
+	for _, d := range done {
+		if err := <-d; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestSchedulingServiceDropsCancelledWaiters(t *testing.T) {
+	s, fake := newTestScheduler(SchedulerConfig{Concurrency: 1})
+
+	first := generate(s, domain.PriorityNormal, "llama3", "first")
+	<-fake.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := s.SendMessage(ctx, domain.NewMessage(domain.ChatID(uuid.New()), "cancelled", domain.UserRole, "llama3"), nil)
+		cancelled <- err
+	}()
+	waitFor(t, func() bool { return waiting(s, "llama3") == 1 })
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if n := waiting(s, "llama3"); n != 0 {
+		t.Errorf("%d requests still wait", n)
+	}
+
+	fake.release <- struct{}{}
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queues) != 0 {
+		t.Errorf("%d idle queues were kept", len(s.queues))
+	}
+}
+
+func TestSchedulingServiceReportsQueuePosition(t *testing.T) {
+	s, fake := newTestScheduler(SchedulerConfig{Concurrency: 1})
+
+	first := generate(s, domain.PriorityNormal, "llama3", "first")
+	<-fake.started
+
+	chunks, err := s.StreamMessage(context.Background(), domain.NewMessage(domain.ChatID(uuid.New()), "streamed", domain.UserRole, "llama3"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk := <-chunks; chunk.QueuePosition != 1 {
+		t.Errorf("got position %d, want 1", chunk.QueuePosition)
+	}
+
+	// A more urgent request moves the stream back
+	high := generate(s, domain.PriorityHigh, "llama3", "high")
+	if chunk := <-chunks; chunk.QueuePosition != 2 {
+		t.Errorf("got position %d, want 2", chunk.QueuePosition)
+	}
+
+	fake.release <- struct{}{}
+	if got := <-fake.started; got != "high" {
+		t.Errorf("started %s, want high", got)
+	}
+	if chunk := <-chunks; chunk.QueuePosition != 1 {
+		t.Errorf("got position %d, want 1", chunk.QueuePosition)
+	}
+
+	fake.release <- struct{}{}
+	if got := <-fake.started; got != "streamed" {
+		t.Errorf("started %s, want streamed", got)
+	}
+	fake.release <- struct{}{}
+	chunk := <-chunks
+	if chunk.Content != "streamed" || !chunk.Done {
+		t.Errorf("got %+v, want the streamed answer", chunk)
+	}
+	for _, d := range []<-chan error{first, high} {
+		if err := <-d; err != nil {
+			t.Error(err)
+		}
+	}
+}
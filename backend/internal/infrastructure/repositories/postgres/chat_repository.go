@@ -3,11 +3,23 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/mariopavlov/nexus/backend/internal/core/domain"
 	"github.com/mariopavlov/nexus/backend/internal/core/ports"
 )
 
+// messageColumns is the column list shared by every message query, in the
+// order scanMessages expects.
+const messageColumns = "id, chat_id, content, role, model, provider, options, status, parent_id, alternative_of, active, fallback, stats, cached, attachments, cited_chunks, tool_calls, tool_call_id, tool_name, created_at, edited_at"
+
+// turnTime places an alternative at the time of the answer it is an
+// alternative of, so regenerating an answer does not move its turn to the
+// end of the chat while every message keeps its own created_at.
+const turnTime = "COALESCE((SELECT o.created_at FROM messages o WHERE o.id = messages.alternative_of), messages.created_at)"
+
 type chatRepository struct {
 	db *sql.DB
 }
@@ -18,16 +30,16 @@ func NewChatRepository(db *sql.DB) ports.ChatRepository {
 
 func (r *chatRepository) Create(ctx context.Context, chat *domain.Chat) error {
 	query := `
-		INSERT INTO chats (id, title, created_at, updated_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO chats (id, title, system_prompt, options, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
-	_, err := r.db.ExecContext(ctx, query, chat.ID, chat.Title, chat.CreatedAt, chat.UpdatedAt)
+	_, err := r.db.ExecContext(ctx, query, chat.ID, chat.Title, chat.SystemPrompt, chat.Options, chat.CreatedAt, chat.UpdatedAt)
 	return err
 }
 
 func (r *chatRepository) GetByID(ctx context.Context, id domain.ChatID) (*domain.Chat, error) {
 	query := `
-		SELECT id, title, created_at, updated_at
+		SELECT id, title, system_prompt, options, created_at, updated_at
 		FROM chats
 		WHERE id = $1
 	`
@@ -35,6 +47,8 @@ func (r *chatRepository) GetByID(ctx context.Context, id domain.ChatID) (*domain
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&chat.ID,
 		&chat.Title,
+		&chat.SystemPrompt,
+		&chat.Options,
 		&chat.CreatedAt,
 		&chat.UpdatedAt,
 	)
@@ -61,10 +75,10 @@ func (r *chatRepository) GetByID(ctx context.Context, id domain.ChatID) (*domain
 func (r *chatRepository) Update(ctx context.Context, chat *domain.Chat) error {
 	query := `
 		UPDATE chats
-		SET title = $1, updated_at = $2
-		WHERE id = $3
+		SET title = $1, system_prompt = $2, options = $3, updated_at = $4
+		WHERE id = $5
 	`
-	_, err := r.db.ExecContext(ctx, query, chat.Title, chat.UpdatedAt, chat.ID)
+	_, err := r.db.ExecContext(ctx, query, chat.Title, chat.SystemPrompt, chat.Options, chat.UpdatedAt, chat.ID)
 	return err
 }
 
@@ -76,7 +90,7 @@ func (r *chatRepository) Delete(ctx context.Context, id domain.ChatID) error {
 
 func (r *chatRepository) List(ctx context.Context, limit, offset int) ([]*domain.Chat, error) {
 	query := `
-		SELECT id, title, created_at, updated_at
+		SELECT id, title, system_prompt, options, created_at, updated_at
 		FROM chats
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -93,6 +107,8 @@ func (r *chatRepository) List(ctx context.Context, limit, offset int) ([]*domain
 		err := rows.Scan(
 			&chat.ID,
 			&chat.Title,
+			&chat.SystemPrompt,
+			&chat.Options,
 			&chat.CreatedAt,
 			&chat.UpdatedAt,
 		)
@@ -104,28 +120,66 @@ func (r *chatRepository) List(ctx context.Context, limit, offset int) ([]*domain
 	return chats, nil
 }
 
+// AddMessage saves an active alternative and deactivates the rest of its
+// group in one transaction, so a group never has two active answers.
 func (r *chatRepository) AddMessage(ctx context.Context, chatID domain.ChatID, message *domain.Message) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if message.AlternativeOf != nil && message.Active {
+		query := `
+			UPDATE messages
+			SET active = FALSE
+			WHERE chat_id = $1 AND (id = $2 OR alternative_of = $2)
+		`
+		if _, err := tx.ExecContext(ctx, query, chatID, *message.AlternativeOf); err != nil {
+			return err
+		}
+	}
+
 	query := `
-		INSERT INTO messages (id, chat_id, content, role, model, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO messages (` + messageColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		message.ID,
 		chatID,
 		message.Content,
 		message.Role,
 		message.Model,
+		message.Provider,
+		message.Options,
+		message.Status,
+		message.ParentID,
+		message.AlternativeOf,
+		message.Active,
+		message.Fallback,
+		message.Stats,
+		message.Cached,
+		message.Attachments,
+		message.CitedChunks,
+		message.ToolCalls,
+		message.ToolCallID,
+		message.ToolName,
 		message.CreatedAt,
+		message.EditedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *chatRepository) GetMessages(ctx context.Context, chatID domain.ChatID, limit, offset int) ([]*domain.Message, error) {
 	query := `
-		SELECT id, chat_id, content, role, model, created_at
+		SELECT ` + messageColumns + `
 		FROM messages
 		WHERE chat_id = $1
-		ORDER BY created_at ASC
+		ORDER BY ` + turnTime + ` ASC, active DESC, created_at ASC
 		LIMIT $2 OFFSET $3
 	`
 	rows, err := r.db.QueryContext(ctx, query, chatID, limit, offset)
@@ -134,6 +188,278 @@ func (r *chatRepository) GetMessages(ctx context.Context, chatID domain.ChatID,
 	}
 	defer rows.Close()
 
+	return scanMessages(rows)
+}
+
+// GetRecentMessages returns the latest limit messages in chronological order.
+// Inactive alternatives are left out since they are not part of the context.
+func (r *chatRepository) GetRecentMessages(ctx context.Context, chatID domain.ChatID, limit int) ([]*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM (
+			SELECT ` + messageColumns + `, ` + turnTime + ` AS turn_time
+			FROM messages
+			WHERE chat_id = $1 AND active
+			ORDER BY turn_time DESC, created_at DESC
+			LIMIT $2
+		) recent
+		ORDER BY turn_time ASC, created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, chatID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+func (r *chatRepository) GetAllMessages(ctx context.Context, chatID domain.ChatID) ([]*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE chat_id = $1
+		ORDER BY ` + turnTime + ` ASC, active DESC, created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+// GetAncestors walks parent_id up from id. Alternatives of an answer share
+// its parent, so every step is swapped for the active member of its group.
+func (r *chatRepository) GetAncestors(ctx context.Context, chatID domain.ChatID, id domain.MessageID, limit int) ([]*domain.Message, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT parent_id, COALESCE(alternative_of, id) AS version_of, 1 AS depth
+			FROM messages
+			WHERE chat_id = $1 AND id = $2
+			UNION ALL
+			SELECT m.parent_id, COALESCE(m.alternative_of, m.id), a.depth + 1
+			FROM messages m
+			JOIN ancestors a ON m.id = a.parent_id
+			WHERE a.depth < $3
+		)
+		SELECT ` + messageColumns + `
+		FROM (
+			SELECT v.*, a.depth
+			FROM messages v
+			JOIN ancestors a ON COALESCE(v.alternative_of, v.id) = a.version_of
+			WHERE v.chat_id = $1 AND v.active
+		) path
+		ORDER BY depth DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, chatID, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+func (r *chatRepository) GetMessage(ctx context.Context, chatID domain.ChatID, id domain.MessageID) (*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE chat_id = $1 AND id = $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, chatID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, domain.ErrMessageNotFound
+	}
+	return messages[0], nil
+}
+
+func (r *chatRepository) GetAlternatives(ctx context.Context, chatID domain.ChatID, id domain.MessageID) ([]*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE chat_id = $1 AND (id = $2 OR alternative_of = $2)
+		ORDER BY id = $2 DESC, created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, chatID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
+}
+
+func (r *chatRepository) SetActiveAlternative(ctx context.Context, chatID domain.ChatID, id, active domain.MessageID) error {
+	query := `
+		UPDATE messages
+		SET active = (id = $3)
+		WHERE chat_id = $1 AND (id = $2 OR alternative_of = $2)
+	`
+	_, err := r.db.ExecContext(ctx, query, chatID, id, active)
+	return err
+}
+
+func (r *chatRepository) EditMessage(ctx context.Context, chatID domain.ChatID, id domain.MessageID, content string, editedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO message_edits (id, chat_id, message_id, content, edited_at)
+		SELECT $3, chat_id, id, content, $4
+		FROM messages
+		WHERE chat_id = $1 AND id = $2
+	`
+	result, err := tx.ExecContext(ctx, query, chatID, id, uuid.New(), editedAt)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return domain.ErrMessageNotFound
+	}
+
+	query = `
+		UPDATE messages
+		SET content = $3, edited_at = $4
+		WHERE chat_id = $1 AND id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, chatID, id, content, editedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *chatRepository) GetMessageEdits(ctx context.Context, chatID domain.ChatID, id domain.MessageID) ([]domain.MessageEdit, error) {
+	query := `
+		SELECT id, message_id, content, edited_at, deleted
+		FROM message_edits
+		WHERE chat_id = $1 AND message_id = $2
+		ORDER BY edited_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, chatID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []domain.MessageEdit{}
+	for rows.Next() {
+		var edit domain.MessageEdit
+		if err := rows.Scan(&edit.ID, &edit.MessageID, &edit.Content, &edit.EditedAt, &edit.Deleted); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+// DeleteMessage keeps the rest of the tree intact. An answer with
+// alternatives hands its place over to one of them: the active one, or the
+// oldest when the deleted answer was active. The deleted content is kept in
+// the edit history.
+func (r *chatRepository) DeleteMessage(ctx context.Context, chatID domain.ChatID, id domain.MessageID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID, alternativeOf *domain.MessageID
+	var active bool
+	query := `
+		SELECT parent_id, alternative_of, active
+		FROM messages
+		WHERE chat_id = $1 AND id = $2
+		FOR UPDATE
+	`
+	err = tx.QueryRowContext(ctx, query, chatID, id).Scan(&parentID, &alternativeOf, &active)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	original := id
+	if alternativeOf != nil {
+		original = *alternativeOf
+	}
+
+	var successor *domain.MessageID
+	query = `
+		SELECT id
+		FROM messages
+		WHERE chat_id = $1 AND (id = $2 OR alternative_of = $2) AND id <> $3
+		ORDER BY active DESC, created_at ASC
+		LIMIT 1
+	`
+	err = tx.QueryRowContext(ctx, query, chatID, original, id).Scan(&successor)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// Replies move to the successor so they keep following this turn
+	replyTo := parentID
+	if successor != nil {
+		replyTo = successor
+		if alternativeOf == nil {
+			query = `
+				UPDATE messages
+				SET alternative_of = CASE WHEN id = $2 THEN NULL ELSE $2 END
+				WHERE chat_id = $1 AND alternative_of = $3
+			`
+			if _, err := tx.ExecContext(ctx, query, chatID, *successor, id); err != nil {
+				return err
+			}
+		}
+		if active {
+			query = `UPDATE messages SET active = TRUE WHERE chat_id = $1 AND id = $2`
+			if _, err := tx.ExecContext(ctx, query, chatID, *successor); err != nil {
+				return err
+			}
+		}
+	}
+
+	query = `UPDATE messages SET parent_id = $3 WHERE chat_id = $1 AND parent_id = $2`
+	if _, err := tx.ExecContext(ctx, query, chatID, id, replyTo); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO message_edits (id, chat_id, message_id, content, edited_at, deleted)
+		SELECT $3, chat_id, id, content, NOW(), TRUE
+		FROM messages
+		WHERE chat_id = $1 AND id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, chatID, id, uuid.New()); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM messages WHERE chat_id = $1 AND id = $2`
+	if _, err := tx.ExecContext(ctx, query, chatID, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanMessages(rows *sql.Rows) ([]*domain.Message, error) {
 	var messages []*domain.Message
 	for rows.Next() {
 		msg := &domain.Message{}
@@ -143,12 +469,27 @@ func (r *chatRepository) GetMessages(ctx context.Context, chatID domain.ChatID,
 			&msg.Content,
 			&msg.Role,
 			&msg.Model,
+			&msg.Provider,
+			&msg.Options,
+			&msg.Status,
+			&msg.ParentID,
+			&msg.AlternativeOf,
+			&msg.Active,
+			&msg.Fallback,
+			&msg.Stats,
+			&msg.Cached,
+			&msg.Attachments,
+			&msg.CitedChunks,
+			&msg.ToolCalls,
+			&msg.ToolCallID,
+			&msg.ToolName,
 			&msg.CreatedAt,
+			&msg.EditedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
 		messages = append(messages, msg)
 	}
-	return messages, nil
+	return messages, rows.Err()
 }
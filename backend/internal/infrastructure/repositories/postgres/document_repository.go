@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type documentRepository struct {
+	db *sql.DB
+}
+
+func NewDocumentRepository(db *sql.DB) ports.DocumentRepository {
+	return &documentRepository{db: db}
+}
+
+func (r *documentRepository) CreateDocument(ctx context.Context, document *domain.Document, chunks []*domain.DocumentChunk, model string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO documents (id, chat_id, title, content_type, size, chunk_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		document.ID,
+		document.ChatID,
+		document.Title,
+		document.ContentType,
+		document.Size,
+		document.ChunkCount,
+		document.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO document_chunks (id, document_id, chunk_index, content, model, dimensions, embedding)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	for _, chunk := range chunks {
+		_, err = tx.ExecContext(ctx, query,
+			chunk.ID,
+			chunk.DocumentID,
+			chunk.Index,
+			chunk.Content,
+			model,
+			len(chunk.Embedding),
+			pq.Float32Array(chunk.Embedding),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert chunk %d: %w", chunk.Index, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *documentRepository) GetDocument(ctx context.Context, id uuid.UUID) (*domain.Document, error) {
+	query := `
+		SELECT id, chat_id, title, content_type, size, chunk_count, created_at
+		FROM documents
+		WHERE id = $1
+	`
+	document := &domain.Document{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&document.ID,
+		&document.ChatID,
+		&document.Title,
+		&document.ContentType,
+		&document.Size,
+		&document.ChunkCount,
+		&document.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func (r *documentRepository) ListDocuments(ctx context.Context, chatID *domain.ChatID) ([]*domain.Document, error) {
+	query := `
+		SELECT id, chat_id, title, content_type, size, chunk_count, created_at
+		FROM documents
+		WHERE chat_id IS NOT DISTINCT FROM $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var documents []*domain.Document
+	for rows.Next() {
+		document := &domain.Document{}
+		err := rows.Scan(
+			&document.ID,
+			&document.ChatID,
+			&document.Title,
+			&document.ContentType,
+			&document.Size,
+			&document.ChunkCount,
+			&document.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, rows.Err()
+}
+
+func (r *documentRepository) DeleteDocument(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM documents WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// SearchChunks ranks chunks by cosine similarity with a brute-force scan over
+// the chunks visible to the chat.
+func (r *documentRepository) SearchChunks(ctx context.Context, chatID domain.ChatID, model string, vector []float32, limit int) ([]domain.ChunkSearchResult, error) {
+	query := `
+		SELECT c.id, c.embedding
+		FROM document_chunks c
+		JOIN documents d ON d.id = c.document_id
+		WHERE (d.chat_id = $1 OR d.chat_id IS NULL) AND c.model = $2 AND c.dimensions = $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, chatID, model, len(vector))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	best := &topK{k: limit}
+	for rows.Next() {
+		var id uuid.UUID
+		var embedding pq.Float32Array
+		if err := rows.Scan(&id, &embedding); err != nil {
+			return nil, err
+		}
+		best.add(id.String(), cosineSimilarity(vector, embedding))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(best.items) == 0 {
+		return []domain.ChunkSearchResult{}, nil
+	}
+
+	ids := make([]string, len(best.items))
+	for i, item := range best.items {
+		ids[i] = item.id
+	}
+
+	query = `
+		SELECT c.id, c.document_id, c.chunk_index, c.content, d.title
+		FROM document_chunks c
+		JOIN documents d ON d.id = c.document_id
+		WHERE c.id = ANY($1)
+	`
+	chunkRows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer chunkRows.Close()
+
+	type chunkWithTitle struct {
+		chunk *domain.DocumentChunk
+		title string
+	}
+	byID := make(map[string]chunkWithTitle, len(ids))
+	for chunkRows.Next() {
+		chunk := &domain.DocumentChunk{}
+		var title string
+		if err := chunkRows.Scan(&chunk.ID, &chunk.DocumentID, &chunk.Index, &chunk.Content, &title); err != nil {
+			return nil, err
+		}
+		byID[chunk.ID.String()] = chunkWithTitle{chunk: chunk, title: title}
+	}
+	if err := chunkRows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.ChunkSearchResult, 0, len(best.items))
+	for _, item := range best.items {
+		found, ok := byID[item.id]
+		if !ok {
+			continue
+		}
+		results = append(results, domain.ChunkSearchResult{
+			Chunk:         found.chunk,
+			DocumentTitle: found.title,
+			Score:         item.score,
+		})
+	}
+	return results, nil
+}
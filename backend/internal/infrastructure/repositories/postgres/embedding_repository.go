@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type embeddingRepository struct {
+	db *sql.DB
+}
+
+func NewEmbeddingRepository(db *sql.DB) ports.EmbeddingRepository {
+	return &embeddingRepository{db: db}
+}
+
+func (r *embeddingRepository) SaveMessageEmbedding(ctx context.Context, message *domain.Message, model string, embedding []float32) error {
+	query := `
+		INSERT INTO message_embeddings (message_id, chat_id, model, dimensions, embedding, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (message_id) DO UPDATE
+		SET model = EXCLUDED.model, dimensions = EXCLUDED.dimensions, embedding = EXCLUDED.embedding, created_at = EXCLUDED.created_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		message.ID,
+		message.ChatID,
+		model,
+		len(embedding),
+		pq.Float32Array(embedding),
+		time.Now(),
+	)
+	return err
+}
+
+// SearchMessages ranks messages by cosine similarity with a brute-force scan
+// over all embeddings of the model.
+func (r *embeddingRepository) SearchMessages(ctx context.Context, model string, vector []float32, limit int) ([]domain.MessageSearchResult, error) {
+	query := `
+		SELECT message_id, embedding
+		FROM message_embeddings
+		WHERE model = $1 AND dimensions = $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, model, len(vector))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	best := &topK{k: limit}
+	for rows.Next() {
+		var id uuid.UUID
+		var embedding pq.Float32Array
+		if err := rows.Scan(&id, &embedding); err != nil {
+			return nil, err
+		}
+		best.add(id.String(), cosineSimilarity(vector, embedding))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(best.items) == 0 {
+		return []domain.MessageSearchResult{}, nil
+	}
+
+	ids := make([]string, len(best.items))
+	for i, item := range best.items {
+		ids[i] = item.id
+	}
+
+	query = `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE id = ANY($1)
+	`
+	messageRows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer messageRows.Close()
+
+	messages, err := scanMessages(messageRows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*domain.Message, len(messages))
+	for _, msg := range messages {
+		byID[uuid.UUID(msg.ID).String()] = msg
+	}
+
+	results := make([]domain.MessageSearchResult, 0, len(best.items))
+	for _, item := range best.items {
+		msg, ok := byID[item.id]
+		if !ok {
+			continue
+		}
+		results = append(results, domain.MessageSearchResult{
+			ChatID:  msg.ChatID,
+			Message: msg,
+			Score:   item.score,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type summaryRepository struct {
+	db *sql.DB
+}
+
+func NewSummaryRepository(db *sql.DB) ports.SummaryRepository {
+	return &summaryRepository{db: db}
+}
+
+func (r *summaryRepository) SaveSummary(ctx context.Context, summary *domain.ChatSummary) error {
+	query := `
+		INSERT INTO chat_summaries (id, chat_id, content, model, message_count, covered_message_id, covered_until, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		summary.ID,
+		summary.ChatID,
+		summary.Content,
+		summary.Model,
+		summary.MessageCount,
+		summary.CoveredMessageID,
+		summary.CoveredUntil,
+		summary.CreatedAt,
+	)
+	return err
+}
+
+func (r *summaryRepository) GetLatestSummary(ctx context.Context, chatID domain.ChatID, messageIDs []domain.MessageID) (*domain.ChatSummary, error) {
+	ids := make([]string, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = uuid.UUID(id).String()
+	}
+
+	query := `
+		SELECT id, chat_id, content, model, message_count, covered_message_id, covered_until, created_at
+		FROM chat_summaries
+		WHERE chat_id = $1 AND covered_message_id = ANY($2)
+		ORDER BY covered_until DESC
+		LIMIT 1
+	`
+	summary := &domain.ChatSummary{}
+	err := r.db.QueryRowContext(ctx, query, chatID, pq.Array(ids)).Scan(
+		&summary.ID,
+		&summary.ChatID,
+		&summary.Content,
+		&summary.Model,
+		&summary.MessageCount,
+		&summary.CoveredMessageID,
+		&summary.CoveredUntil,
+		&summary.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
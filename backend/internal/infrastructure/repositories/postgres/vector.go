@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"math"
+	"sort"
+)
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+type scoredID struct {
+	id    string
+	score float64
+}
+
+// topK keeps the k highest scoring IDs seen so far, best first.
+type topK struct {
+	k     int
+	items []scoredID
+}
+
+func (t *topK) add(id string, score float64) {
+	if len(t.items) == t.k && score <= t.items[len(t.items)-1].score {
+		return
+	}
+
+	i := sort.Search(len(t.items), func(i int) bool { return t.items[i].score < score })
+	t.items = append(t.items, scoredID{})
+	copy(t.items[i+1:], t.items[i:])
+	t.items[i] = scoredID{id: id, score: score}
+	if len(t.items) > t.k {
+		t.items = t.items[:t.k]
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// fileAttachmentStore keeps every attachment in a file named after its ID,
+// in one directory per chat so a chat can be removed in one go.
+type fileAttachmentStore struct {
+	dir string
+}
+
+func NewFileAttachmentStore(dir string) (ports.AttachmentStore, error) {
+	if dir == "" {
+		dir = "data/attachments"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+	return &fileAttachmentStore{dir: dir}, nil
+}
+
+func (s *fileAttachmentStore) chatDir(chatID domain.ChatID) string {
+	return filepath.Join(s.dir, uuid.UUID(chatID).String())
+}
+
+func (s *fileAttachmentStore) Save(ctx context.Context, chatID domain.ChatID, attachment *domain.Attachment) error {
+	dir := s.chatDir(chatID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create chat directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create attachment file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(attachment.Data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write attachment: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write attachment: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, attachment.ID.String())); err != nil {
+		return fmt.Errorf("failed to store attachment: %w", err)
+	}
+	return nil
+}
+
+func (s *fileAttachmentStore) Load(ctx context.Context, chatID domain.ChatID, id uuid.UUID) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.chatDir(chatID), id.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return data, nil
+}
+
+func (s *fileAttachmentStore) DeleteChat(ctx context.Context, chatID domain.ChatID) error {
+	if err := os.RemoveAll(s.chatDir(chatID)); err != nil {
+		return fmt.Errorf("failed to delete chat attachments: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+// CurrentTimeTool tells the model the current date and time, which it
+// otherwise cannot know.
+type CurrentTimeTool struct{}
+
+func NewCurrentTimeTool() ports.Tool {
+	return &CurrentTimeTool{}
+}
+
+func (t *CurrentTimeTool) Name() string {
+	return "current_time"
+}
+
+func (t *CurrentTimeTool) Description() string {
+	return "Get the current date and time, optionally in a given IANA time zone."
+}
+
+func (t *CurrentTimeTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"timezone": {
+				"type": "string",
+				"description": "IANA time zone such as Europe/Sofia, defaults to UTC"
+			}
+		}
+	}`)
+}
+
+func (t *CurrentTimeTool) Execute(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Timezone string `json:"timezone"`
+	}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	location := time.UTC
+	if args.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(args.Timezone)
+		if err != nil {
+			return "", fmt.Errorf("unknown time zone %q", args.Timezone)
+		}
+	}
+
+	return time.Now().In(location).Format("Monday, 2 January 2006 15:04:05 MST"), nil
+}
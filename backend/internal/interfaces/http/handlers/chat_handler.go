@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -22,16 +27,183 @@ func NewChatHandler(chatUseCase ports.ChatUseCase) *ChatHandler {
 }
 
 type CreateChatRequest struct {
-	Title string `json:"title" binding:"required"`
+	Title        string                    `json:"title" binding:"required"`
+	SystemPrompt string                    `json:"system_prompt"`
+	Options      *domain.GenerationOptions `json:"options"`
 }
 
 type SendMessageRequest struct {
+	Content string                    `json:"content" binding:"required"`
+	Model   string                    `json:"model" binding:"required"`
+	Options *domain.GenerationOptions `json:"options"`
+	// ParentID branches from that message instead of the latest one
+	ParentID *uuid.UUID `json:"parent_id"`
+	// Format is "json" or a JSON Schema the answer must follow
+	Format json.RawMessage `json:"format"`
+	// FormatRetry re-prompts once when the answer does not match Format
+	FormatRetry bool `json:"format_retry"`
+}
+
+// parent converts an optional branch point to a message ID.
+func parent(id *uuid.UUID) *domain.MessageID {
+	if id == nil {
+		return nil
+	}
+	parentID := domain.MessageID(*id)
+	return &parentID
+}
+
+// ResponseFormat returns the requested answer format, or nil for free text.
+func (r *SendMessageRequest) ResponseFormat() (*domain.ResponseFormat, error) {
+	if len(r.Format) == 0 || string(r.Format) == "null" {
+		return nil, nil
+	}
+	return domain.NewResponseFormat(r.Format, r.FormatRetry)
+}
+
+// CompareRequest sends one prompt to several models at once.
+type CompareRequest struct {
+	Content string                    `json:"content" binding:"required"`
+	Models  []string                  `json:"models" binding:"required,min=1,max=8,dive,required"`
+	Options *domain.GenerationOptions `json:"options"`
+	// ParentID branches from that message instead of the latest one
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+type EditMessageRequest struct {
 	Content string `json:"content" binding:"required"`
-	Model   string `json:"model" binding:"required"`
+}
+
+// RegenerateRequest optionally answers again with another model or options.
+type RegenerateRequest struct {
+	Model   string                    `json:"model"`
+	Options *domain.GenerationOptions `json:"options"`
 }
 
 type UpdateChatRequest struct {
-	Title string `json:"title" binding:"required"`
+	Title        string                    `json:"title" binding:"required"`
+	SystemPrompt *string                   `json:"system_prompt"`
+	Options      *domain.GenerationOptions `json:"options"`
+}
+
+const (
+	maxAttachmentSize = 20 << 20
+	maxAttachments    = 8
+)
+
+// bindSendMessage accepts a JSON body, or a multipart form whose "images"
+// files are attached to the message. In a form the options are a JSON
+// encoded field.
+func bindSendMessage(c *gin.Context) (*SendMessageRequest, domain.Attachments, error) {
+	var req SendMessageRequest
+	if c.ContentType() != "multipart/form-data" {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return nil, nil, err
+		}
+		return &req, nil, nil
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Content = c.PostForm("content")
+	req.Model = c.PostForm("model")
+	if req.Content == "" || req.Model == "" {
+		return nil, nil, errors.New("content and model are required")
+	}
+	if options := c.PostForm("options"); options != "" {
+		if err := json.Unmarshal([]byte(options), &req.Options); err != nil {
+			return nil, nil, fmt.Errorf("invalid options: %w", err)
+		}
+	}
+	// A bare "json" is accepted in a form, anything else must be a schema
+	if format := c.PostForm("format"); format == "json" {
+		req.Format = json.RawMessage(`"json"`)
+	} else if format != "" {
+		req.Format = json.RawMessage(format)
+	}
+	req.FormatRetry, _ = strconv.ParseBool(c.PostForm("format_retry"))
+	if parentID := c.PostForm("parent_id"); parentID != "" {
+		id, err := uuid.Parse(parentID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid parent_id: %w", err)
+		}
+		req.ParentID = &id
+	}
+
+	files := form.File["images"]
+	if len(files) > maxAttachments {
+		return nil, nil, fmt.Errorf("at most %d images can be attached", maxAttachments)
+	}
+
+	var attachments domain.Attachments
+	for _, fileHeader := range files {
+		if fileHeader.Size > maxAttachmentSize {
+			return nil, nil, fmt.Errorf("image %s is too large", fileHeader.Filename)
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize))
+		file.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Sniff the type instead of trusting the client
+		attachments = append(attachments, domain.NewAttachment(fileHeader.Filename, http.DetectContentType(data), data))
+	}
+
+	return &req, attachments, nil
+}
+
+func sendMessageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrUnsupportedAttachment):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, domain.ErrVisionNotSupported):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrInvalidStructuredOutput):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrMessageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotAnAnswer):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrGenerationCancelled):
+		// Another request cancelled the generation before it produced anything
+		return http.StatusConflict
+	default:
+		return modelErrorStatus(err)
+	}
+}
+
+// messageParams parses the chat and message IDs of a message route, replying
+// with an error when either is malformed.
+func messageParams(c *gin.Context) (domain.ChatID, domain.MessageID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Printf("Invalid chat ID format: %v, ID: %s", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat ID format",
+			"details": err.Error(),
+		})
+		return domain.ChatID{}, domain.MessageID{}, false
+	}
+
+	messageID, err := uuid.Parse(c.Param("messageId"))
+	if err != nil {
+		log.Printf("Invalid message ID format: %v, ID: %s", err, c.Param("messageId"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid message ID format",
+			"details": err.Error(),
+		})
+		return domain.ChatID{}, domain.MessageID{}, false
+	}
+	return domain.ChatID(id), domain.MessageID(messageID), true
 }
 
 func (h *ChatHandler) RegisterRoutes(r *gin.Engine) {
@@ -41,8 +213,20 @@ func (h *ChatHandler) RegisterRoutes(r *gin.Engine) {
 	r.PUT("/chats/:id", h.UpdateChat)
 	r.DELETE("/chats/:id", h.DeleteChat)
 	r.POST("/chats/:id/messages", h.SendMessage)
+	r.POST("/chats/:id/messages/stream", h.StreamMessage)
+	r.PUT("/chats/:id/messages/:messageId", h.EditMessage)
+	r.DELETE("/chats/:id/messages/:messageId", h.DeleteMessage)
+	r.GET("/chats/:id/messages/:messageId/edits", h.ListMessageEdits)
+	r.POST("/chats/:id/messages/:messageId/cancel", h.CancelGeneration)
+	r.POST("/chats/:id/messages/:messageId/regenerate", h.RegenerateMessage)
+	r.GET("/chats/:id/messages/:messageId/alternatives", h.ListAlternatives)
+	r.POST("/chats/:id/messages/:messageId/select", h.SelectAlternative)
+	r.POST("/chats/:id/compare", h.CompareModels)
 	r.GET("/chats/:id/messages", h.GetMessages)
+	r.GET("/chats/:id/tree", h.GetChatTree)
+	r.GET("/chats/:id/branches", h.ListBranches)
 	r.GET("/models", h.ListModels)
+	r.GET("/models/*name", h.GetModel)
 }
 
 func (h *ChatHandler) CreateChat(c *gin.Context) {
@@ -53,7 +237,7 @@ func (h *ChatHandler) CreateChat(c *gin.Context) {
 		return
 	}
 
-	chat, err := h.chatUseCase.CreateChat(c.Request.Context(), req.Title)
+	chat, err := h.chatUseCase.CreateChat(c.Request.Context(), req.Title, req.SystemPrompt, req.Options)
 	if err != nil {
 		log.Printf("Failed to create chat: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -150,7 +334,7 @@ func (h *ChatHandler) UpdateChat(c *gin.Context) {
 		return
 	}
 
-	chat, err := h.chatUseCase.UpdateChat(c.Request.Context(), domain.ChatID(id), req.Title)
+	chat, err := h.chatUseCase.UpdateChat(c.Request.Context(), domain.ChatID(id), req.Title, req.SystemPrompt, req.Options)
 	if err != nil {
 		log.Printf("Failed to update chat: %v, ID: %s", err, id)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -175,17 +359,23 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 		return
 	}
 
-	var req SendMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("Failed to bind JSON: %v", err)
+	req, attachments, err := bindSendMessage(c)
+	if err != nil {
+		log.Printf("Failed to bind request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, err := req.ResponseFormat()
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	message, err := h.chatUseCase.SendMessage(c.Request.Context(), domain.ChatID(id), req.Content, req.Model)
+	message, err := h.chatUseCase.SendMessage(c.Request.Context(), domain.ChatID(id), parent(req.ParentID), req.Content, req.Model, req.Options, attachments, format)
 	if err != nil {
 		log.Printf("Failed to send message: %v, ID: %s", err, id)
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(sendMessageErrorStatus(err), gin.H{
 			"error":   "Failed to send message",
 			"details": err.Error(),
 		})
@@ -196,6 +386,285 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 	c.JSON(http.StatusOK, message)
 }
 
+func (h *ChatHandler) StreamMessage(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Printf("Invalid chat ID format: %v, ID: %s", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat ID format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	req, attachments, err := bindSendMessage(c)
+	if err != nil {
+		log.Printf("Failed to bind request: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, err := req.ResponseFormat()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chunks, err := h.chatUseCase.StreamMessage(c.Request.Context(), domain.ChatID(id), parent(req.ParentID), req.Content, req.Model, req.Options, attachments, format)
+	if err != nil {
+		log.Printf("Failed to stream message: %v, ID: %s", err, id)
+		c.JSON(sendMessageErrorStatus(err), gin.H{
+			"error":   "Failed to stream message",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		chunk, ok := <-chunks
+		if !ok {
+			return false
+		}
+
+		if chunk.Err != nil {
+			log.Printf("Failed to stream message: %v, ID: %s", chunk.Err, id)
+			c.SSEvent("error", gin.H{
+				"error":   "Failed to stream message",
+				"details": chunk.Err.Error(),
+			})
+			return false
+		}
+
+		// The first event tells the client which IDs can cancel the generation
+		if chunk.Started != nil {
+			c.SSEvent("start", chunk.Started)
+			return true
+		}
+
+		if chunk.Done {
+			log.Printf("Successfully streamed message with ID: %s", id)
+			c.SSEvent("done", gin.H{
+				"message_id":  chunk.Message.ID,
+				"done_reason": chunk.DoneReason,
+				"message":     chunk.Message,
+			})
+			return false
+		}
+
+		// Sent while the request waits for the model
+		if chunk.QueuePosition > 0 {
+			c.SSEvent("queued", gin.H{"position": chunk.QueuePosition})
+			return true
+		}
+
+		// Tool calls and their results arrive as complete messages
+		if chunk.Message != nil {
+			c.SSEvent("tool", chunk.Message)
+			return true
+		}
+
+		c.SSEvent("chunk", gin.H{"content": chunk.Content})
+		return true
+	})
+}
+
+func (h *ChatHandler) CompareModels(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Printf("Invalid chat ID format: %v, ID: %s", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat ID format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var req CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comparison, err := h.chatUseCase.CompareModels(c.Request.Context(), domain.ChatID(id), parent(req.ParentID), req.Content, req.Models, req.Options)
+	if err != nil {
+		log.Printf("Failed to compare models: %v, ID: %s", err, id)
+		c.JSON(sendMessageErrorStatus(err), gin.H{
+			"error":   "Failed to compare models",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully compared %d models in chat with ID: %s", len(req.Models), id)
+	c.JSON(http.StatusOK, comparison)
+}
+
+// CancelGeneration stops an in-flight generation, identified by the user
+// message that started it or by the answer ID from the stream's start event.
+func (h *ChatHandler) CancelGeneration(c *gin.Context) {
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	err := h.chatUseCase.CancelGeneration(c.Request.Context(), chatID, messageID)
+	if err != nil {
+		log.Printf("Failed to cancel generation: %v, ID: %s", err, uuid.UUID(messageID))
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrGenerationNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to cancel generation",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully cancelled generation for message ID: %s", uuid.UUID(messageID))
+	c.Status(http.StatusAccepted)
+}
+
+func (h *ChatHandler) EditMessage(c *gin.Context) {
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	var req EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.chatUseCase.EditMessage(c.Request.Context(), chatID, messageID, req.Content)
+	if err != nil {
+		log.Printf("Failed to edit message: %v, ID: %s", err, uuid.UUID(messageID))
+		c.JSON(sendMessageErrorStatus(err), gin.H{
+			"error":   "Failed to edit message",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully edited message with ID: %s", uuid.UUID(messageID))
+	c.JSON(http.StatusOK, message)
+}
+
+func (h *ChatHandler) DeleteMessage(c *gin.Context) {
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chatUseCase.DeleteMessage(c.Request.Context(), chatID, messageID); err != nil {
+		log.Printf("Failed to delete message: %v, ID: %s", err, uuid.UUID(messageID))
+		c.JSON(sendMessageErrorStatus(err), gin.H{
+			"error":   "Failed to delete message",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully deleted message with ID: %s", uuid.UUID(messageID))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ChatHandler) ListMessageEdits(c *gin.Context) {
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	edits, err := h.chatUseCase.ListMessageEdits(c.Request.Context(), chatID, messageID)
+	if err != nil {
+		log.Printf("Failed to list message edits: %v, ID: %s", err, uuid.UUID(messageID))
+		c.JSON(sendMessageErrorStatus(err), gin.H{
+			"error":   "Failed to list message edits",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully listed edits of message with ID: %s", uuid.UUID(messageID))
+	c.JSON(http.StatusOK, edits)
+}
+
+func (h *ChatHandler) RegenerateMessage(c *gin.Context) {
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional, without one the turn is answered as before
+	var req RegenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.chatUseCase.RegenerateMessage(c.Request.Context(), chatID, messageID, req.Model, req.Options)
+	if err != nil {
+		log.Printf("Failed to regenerate message: %v, ID: %s", err, uuid.UUID(messageID))
+		c.JSON(sendMessageErrorStatus(err), gin.H{
+			"error":   "Failed to regenerate message",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully regenerated message with ID: %s", uuid.UUID(messageID))
+	c.JSON(http.StatusOK, message)
+}
+
+func (h *ChatHandler) ListAlternatives(c *gin.Context) {
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	messages, err := h.chatUseCase.ListAlternatives(c.Request.Context(), chatID, messageID)
+	if err != nil {
+		log.Printf("Failed to list alternatives: %v, ID: %s", err, uuid.UUID(messageID))
+		c.JSON(sendMessageErrorStatus(err), gin.H{
+			"error":   "Failed to list alternatives",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully listed alternatives of message with ID: %s", uuid.UUID(messageID))
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *ChatHandler) SelectAlternative(c *gin.Context) {
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	message, err := h.chatUseCase.SelectAlternative(c.Request.Context(), chatID, messageID)
+	if err != nil {
+		log.Printf("Failed to select alternative: %v, ID: %s", err, uuid.UUID(messageID))
+		c.JSON(sendMessageErrorStatus(err), gin.H{
+			"error":   "Failed to select alternative",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully selected alternative with ID: %s", uuid.UUID(messageID))
+	c.JSON(http.StatusOK, message)
+}
+
 func (h *ChatHandler) GetMessages(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -227,14 +696,100 @@ func (h *ChatHandler) GetMessages(c *gin.Context) {
 	c.JSON(http.StatusOK, messages)
 }
 
+func (h *ChatHandler) GetChatTree(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Printf("Invalid chat ID format: %v, ID: %s", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat ID format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	tree, err := h.chatUseCase.GetChatTree(c.Request.Context(), domain.ChatID(id))
+	if err != nil {
+		log.Printf("Failed to get chat tree: %v, ID: %s", err, id)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get chat tree",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully retrieved tree of chat with ID: %s", id)
+	c.JSON(http.StatusOK, tree)
+}
+
+func (h *ChatHandler) ListBranches(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Printf("Invalid chat ID format: %v, ID: %s", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat ID format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	branches, err := h.chatUseCase.ListBranches(c.Request.Context(), domain.ChatID(id))
+	if err != nil {
+		log.Printf("Failed to list branches: %v, ID: %s", err, id)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to list branches",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully listed branches of chat with ID: %s", id)
+	c.JSON(http.StatusOK, branches)
+}
+
 func (h *ChatHandler) ListModels(c *gin.Context) {
+	// The rich catalog is opt-in so existing clients keep receiving names only
+	if detailed, _ := strconv.ParseBool(c.Query("detailed")); detailed {
+		models, err := h.chatUseCase.ListModels(c.Request.Context())
+		if err != nil {
+			log.Printf("Failed to list models: %v", err)
+			c.JSON(modelErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		log.Printf("Successfully listed models")
+		c.JSON(http.StatusOK, models)
+		return
+	}
+
 	models, err := h.chatUseCase.ListAvailableModels(c.Request.Context())
 	if err != nil {
 		log.Printf("Failed to list models: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(modelErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	log.Printf("Successfully listed models")
 	c.JSON(http.StatusOK, models)
 }
+
+func (h *ChatHandler) GetModel(c *gin.Context) {
+	// Model names may contain slashes, e.g. "ollama/llama3" or "hf.co/org/model"
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Model name is required"})
+		return
+	}
+
+	model, err := h.chatUseCase.GetModelInfo(c.Request.Context(), name)
+	if err != nil {
+		log.Printf("Failed to get model: %v, name: %s", err, name)
+		c.JSON(modelErrorStatus(err), gin.H{
+			"error":   "Failed to get model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully retrieved model: %s", name)
+	c.JSON(http.StatusOK, model)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+const maxDocumentSize = 10 << 20
+
+type DocumentHandler struct {
+	documentUseCase ports.DocumentUseCase
+}
+
+func NewDocumentHandler(documentUseCase ports.DocumentUseCase) *DocumentHandler {
+	return &DocumentHandler{
+		documentUseCase: documentUseCase,
+	}
+}
+
+func (h *DocumentHandler) RegisterRoutes(r *gin.Engine) {
+	r.POST("/documents", h.UploadDocument)
+	r.GET("/documents", h.ListDocuments)
+	r.DELETE("/documents/:documentId", h.DeleteDocument)
+	r.POST("/chats/:id/documents", h.UploadDocument)
+	r.GET("/chats/:id/documents", h.ListDocuments)
+}
+
+// chatScope returns the chat of a /chats/:id/... route, or nil for the
+// workspace routes.
+func chatScope(c *gin.Context) (*domain.ChatID, error) {
+	param := c.Param("id")
+	if param == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(param)
+	if err != nil {
+		return nil, err
+	}
+	chatID := domain.ChatID(id)
+	return &chatID, nil
+}
+
+// documentContentType prefers the extension because browsers often send
+// markdown files as application/octet-stream.
+func documentContentType(filename, header string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".md", ".markdown":
+		return "text/markdown"
+	case ".txt", ".text":
+		return "text/plain"
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return header
+	}
+	return mediaType
+}
+
+func (h *DocumentHandler) UploadDocument(c *gin.Context) {
+	chatID, err := chatScope(c)
+	if err != nil {
+		log.Printf("Invalid chat ID format: %v, ID: %s", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat ID format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "A file field is required",
+			"details": err.Error(),
+		})
+		return
+	}
+	if fileHeader.Size > maxDocumentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Document is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Printf("Failed to open uploaded document: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxDocumentSize))
+	if err != nil {
+		log.Printf("Failed to read uploaded document: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	title := c.PostForm("title")
+	if title == "" {
+		title = fileHeader.Filename
+	}
+	contentType := documentContentType(fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
+
+	document, err := h.documentUseCase.UploadDocument(c.Request.Context(), chatID, title, contentType, content)
+	if err != nil {
+		log.Printf("Failed to upload document: %v, title: %s", err, title)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, domain.ErrUnsupportedDocument):
+			status = http.StatusUnsupportedMediaType
+		case errors.Is(err, domain.ErrEmptyDocument):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to upload document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully uploaded document with ID: %s", document.ID)
+	c.JSON(http.StatusOK, document)
+}
+
+func (h *DocumentHandler) ListDocuments(c *gin.Context) {
+	chatID, err := chatScope(c)
+	if err != nil {
+		log.Printf("Invalid chat ID format: %v, ID: %s", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat ID format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	documents, err := h.documentUseCase.ListDocuments(c.Request.Context(), chatID)
+	if err != nil {
+		log.Printf("Failed to list documents: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Successfully listed documents")
+	c.JSON(http.StatusOK, documents)
+}
+
+func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("documentId"))
+	if err != nil {
+		log.Printf("Invalid document ID format: %v, ID: %s", err, c.Param("documentId"))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid document ID format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.documentUseCase.DeleteDocument(c.Request.Context(), id); err != nil {
+		log.Printf("Failed to delete document: %v, ID: %s", err, id)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully deleted document with ID: %s", id)
+	c.Status(http.StatusNoContent)
+}
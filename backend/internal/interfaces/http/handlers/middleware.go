@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+)
+
+// PriorityMiddleware schedules the generations of a request with the
+// priority named in its X-Priority header. Anyone may yield with a low
+// priority, but jumping the queue takes the token in X-Priority-Token; an
+// empty token reserves high priority for the server itself.
+func PriorityMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		priority, err := domain.ParsePriority(c.GetHeader("X-Priority"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if priority == domain.PriorityHigh {
+			given := c.GetHeader("X-Priority-Token")
+			if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "high priority requires a valid X-Priority-Token"})
+				return
+			}
+		}
+
+		c.Request = c.Request.WithContext(domain.WithPriority(c.Request.Context(), priority))
+		c.Next()
+	}
+}
+
+// AdminMiddleware lets through only requests that carry the admin token as
+// "Authorization: Bearer <token>". An empty token locks the routes for
+// everyone.
+func AdminMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin routes require a valid bearer token"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mariopavlov/nexus/backend/internal/core/domain"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type ModelHandler struct {
+	modelUseCase ports.ModelUseCase
+	// adminToken guards every route, they change the models of every host
+	adminToken string
+}
+
+func NewModelHandler(modelUseCase ports.ModelUseCase, adminToken string) *ModelHandler {
+	return &ModelHandler{
+		modelUseCase: modelUseCase,
+		adminToken:   adminToken,
+	}
+}
+
+type PullModelRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type CopyModelRequest struct {
+	Source      string `json:"source" binding:"required"`
+	Destination string `json:"destination" binding:"required"`
+}
+
+type PreloadModelRequest struct {
+	Name string `json:"name" binding:"required"`
+	// KeepAlive is a Go duration such as "10m"; negative keeps the model loaded
+	KeepAlive string `json:"keep_alive"`
+}
+
+func (h *ModelHandler) RegisterRoutes(r *gin.Engine) {
+	admin := r.Group("/admin/models", AdminMiddleware(h.adminToken))
+	admin.POST("/pull", h.PullModel)
+	admin.POST("/copy", h.CopyModel)
+	admin.POST("/preload", h.PreloadModel)
+	admin.DELETE("/*name", h.DeleteModel)
+}
+
+// modelErrorStatus maps errors coming from a model provider.
+func modelErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidModelCopy):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrModelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrModelFailed):
+		return http.StatusBadGateway
+	case errors.Is(err, domain.ErrProviderUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, domain.ErrProviderTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, domain.ErrQueueFull):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *ModelHandler) PullModel(c *gin.Context) {
+	var req PullModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	progress, err := h.modelUseCase.PullModel(c.Request.Context(), req.Name)
+	if err != nil {
+		log.Printf("Failed to pull model: %v, name: %s", err, req.Name)
+		c.JSON(modelErrorStatus(err), gin.H{
+			"error":   "Failed to pull model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		update, ok := <-progress
+		if !ok {
+			return false
+		}
+
+		if update.Err != nil {
+			log.Printf("Failed to pull model: %v, name: %s", update.Err, req.Name)
+			c.SSEvent("error", gin.H{
+				"error":   "Failed to pull model",
+				"details": update.Err.Error(),
+			})
+			return false
+		}
+
+		if update.Status == "success" {
+			log.Printf("Successfully pulled model: %s", req.Name)
+			c.SSEvent("done", update)
+			return false
+		}
+
+		c.SSEvent("progress", update)
+		return true
+	})
+}
+
+func (h *ModelHandler) DeleteModel(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Model name is required"})
+		return
+	}
+
+	if err := h.modelUseCase.DeleteModel(c.Request.Context(), name); err != nil {
+		log.Printf("Failed to delete model: %v, name: %s", err, name)
+		c.JSON(modelErrorStatus(err), gin.H{
+			"error":   "Failed to delete model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully deleted model: %s", name)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ModelHandler) CopyModel(c *gin.Context) {
+	var req CopyModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.modelUseCase.CopyModel(c.Request.Context(), req.Source, req.Destination); err != nil {
+		log.Printf("Failed to copy model: %v, source: %s", err, req.Source)
+		c.JSON(modelErrorStatus(err), gin.H{
+			"error":   "Failed to copy model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully copied model %s to %s", req.Source, req.Destination)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ModelHandler) PreloadModel(c *gin.Context) {
+	var req PreloadModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var keepAlive time.Duration
+	if req.KeepAlive != "" {
+		var err error
+		keepAlive, err = time.ParseDuration(req.KeepAlive)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid keep_alive duration",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.modelUseCase.PreloadModel(c.Request.Context(), req.Name, keepAlive); err != nil {
+		log.Printf("Failed to preload model: %v, name: %s", err, req.Name)
+		c.JSON(modelErrorStatus(err), gin.H{
+			"error":   "Failed to preload model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully preloaded model: %s", req.Name)
+	c.Status(http.StatusNoContent)
+}
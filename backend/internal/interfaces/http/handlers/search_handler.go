@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mariopavlov/nexus/backend/internal/core/ports"
+)
+
+type SearchHandler struct {
+	searchUseCase ports.SearchUseCase
+}
+
+func NewSearchHandler(searchUseCase ports.SearchUseCase) *SearchHandler {
+	return &SearchHandler{
+		searchUseCase: searchUseCase,
+	}
+}
+
+func (h *SearchHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/search/semantic", h.SemanticSearch)
+}
+
+func (h *SearchHandler) SemanticSearch(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	results, err := h.searchUseCase.SemanticSearch(c.Request.Context(), query, limit)
+	if err != nil {
+		log.Printf("Failed to search messages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search messages",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Successfully searched messages, %d results", len(results))
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN provider VARCHAR(100) NOT NULL DEFAULT '';
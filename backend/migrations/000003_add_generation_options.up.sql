@@ -0,0 +1,2 @@
+ALTER TABLE chats ADD COLUMN options JSONB;
+ALTER TABLE messages ADD COLUMN options JSONB;
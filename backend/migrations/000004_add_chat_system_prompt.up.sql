@@ -0,0 +1 @@
+ALTER TABLE chats ADD COLUMN system_prompt TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+CREATE TABLE chat_summaries (
+    id UUID PRIMARY KEY,
+    chat_id UUID NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    message_count INTEGER NOT NULL,
+    covered_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_chat_summaries_chat_id_covered_until ON chat_summaries(chat_id, covered_until DESC);
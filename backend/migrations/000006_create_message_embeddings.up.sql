@@ -0,0 +1,13 @@
+-- Embeddings live in their own table keyed by message so the REAL[] column can
+-- later be converted to a pgvector column and indexed without touching messages:
+--   ALTER TABLE message_embeddings ALTER COLUMN embedding TYPE vector USING embedding::vector;
+CREATE TABLE message_embeddings (
+    message_id UUID PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    chat_id UUID NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    model VARCHAR(100) NOT NULL,
+    dimensions INTEGER NOT NULL,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_message_embeddings_model ON message_embeddings(model, dimensions);
@@ -0,0 +1,3 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS cited_chunks;
+DROP TABLE IF EXISTS document_chunks;
+DROP TABLE IF EXISTS documents;
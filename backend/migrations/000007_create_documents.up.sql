@@ -0,0 +1,26 @@
+CREATE TABLE documents (
+    id UUID PRIMARY KEY,
+    chat_id UUID REFERENCES chats(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size INTEGER NOT NULL,
+    chunk_count INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_documents_chat_id ON documents(chat_id);
+
+-- See message_embeddings for how the embedding column can move to pgvector
+CREATE TABLE document_chunks (
+    id UUID PRIMARY KEY,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    dimensions INTEGER NOT NULL,
+    embedding REAL[] NOT NULL
+);
+
+CREATE INDEX idx_document_chunks_document_id ON document_chunks(document_id);
+
+ALTER TABLE messages ADD COLUMN cited_chunks JSONB;
@@ -0,0 +1,3 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS tool_name;
+ALTER TABLE messages DROP COLUMN IF EXISTS tool_call_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS tool_calls;
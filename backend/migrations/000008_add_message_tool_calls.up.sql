@@ -0,0 +1,3 @@
+ALTER TABLE messages ADD COLUMN tool_calls JSONB;
+ALTER TABLE messages ADD COLUMN tool_call_id VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN tool_name VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'completed';
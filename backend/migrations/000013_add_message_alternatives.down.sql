@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_messages_alternative_of;
+ALTER TABLE messages DROP COLUMN IF EXISTS active;
+ALTER TABLE messages DROP COLUMN IF EXISTS alternative_of;
@@ -0,0 +1,4 @@
+ALTER TABLE messages ADD COLUMN alternative_of UUID REFERENCES messages(id) ON DELETE CASCADE;
+ALTER TABLE messages ADD COLUMN active BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE INDEX idx_messages_alternative_of ON messages(alternative_of);
@@ -0,0 +1,20 @@
+ALTER TABLE messages ADD COLUMN parent_id UUID REFERENCES messages(id) ON DELETE CASCADE;
+
+-- Existing chats become a single branch following the active messages
+UPDATE messages m
+SET parent_id = chain.previous
+FROM (
+    SELECT id, LAG(id) OVER (PARTITION BY chat_id ORDER BY created_at, id) AS previous
+    FROM messages
+    WHERE active
+) chain
+WHERE m.id = chain.id;
+
+-- Inactive alternatives share the parent of the active answer of their turn
+UPDATE messages m
+SET parent_id = a.parent_id
+FROM messages a
+WHERE a.active AND NOT m.active
+    AND COALESCE(a.alternative_of, a.id) = COALESCE(m.alternative_of, m.id);
+
+CREATE INDEX idx_messages_parent_id ON messages(parent_id);
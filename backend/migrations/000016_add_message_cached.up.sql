@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN cached BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_chat_summaries_covered_message_id;
+ALTER TABLE chat_summaries DROP COLUMN IF EXISTS covered_message_id;
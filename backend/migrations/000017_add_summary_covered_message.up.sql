@@ -0,0 +1,13 @@
+ALTER TABLE chat_summaries ADD COLUMN covered_message_id UUID REFERENCES messages(id) ON DELETE SET NULL;
+
+-- Summaries so far ended at the message created at covered_until
+UPDATE chat_summaries s
+SET covered_message_id = (
+    SELECT m.id
+    FROM messages m
+    WHERE m.chat_id = s.chat_id AND m.created_at = s.covered_until
+    ORDER BY m.active DESC
+    LIMIT 1
+);
+
+CREATE INDEX idx_chat_summaries_covered_message_id ON chat_summaries(covered_message_id);
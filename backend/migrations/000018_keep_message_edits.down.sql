@@ -0,0 +1,10 @@
+DELETE FROM message_edits e
+WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = e.message_id);
+
+DROP INDEX IF EXISTS idx_message_edits_chat_id_message_id;
+CREATE INDEX idx_message_edits_message_id ON message_edits(message_id);
+
+ALTER TABLE message_edits ADD CONSTRAINT message_edits_message_id_fkey
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE;
+ALTER TABLE message_edits DROP COLUMN IF EXISTS deleted;
+ALTER TABLE message_edits DROP COLUMN IF EXISTS chat_id;
@@ -0,0 +1,14 @@
+-- Edits outlive the message they belong to and only go with the chat
+ALTER TABLE message_edits ADD COLUMN chat_id UUID REFERENCES chats(id) ON DELETE CASCADE;
+ALTER TABLE message_edits ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE message_edits e
+SET chat_id = m.chat_id
+FROM messages m
+WHERE m.id = e.message_id;
+
+ALTER TABLE message_edits ALTER COLUMN chat_id SET NOT NULL;
+ALTER TABLE message_edits DROP CONSTRAINT message_edits_message_id_fkey;
+
+DROP INDEX IF EXISTS idx_message_edits_message_id;
+CREATE INDEX idx_message_edits_chat_id_message_id ON message_edits(chat_id, message_id);